@@ -0,0 +1,629 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+)
+
+// configPath 配置文件路径，可通过 --config 指定
+var configPath = "config.yaml"
+
+// outputRoot 生成结果根目录，可通过 --output-dir 指定
+var outputRoot = "output"
+
+// cliArgs 命令行解析结果，所有命令共用，命令未声明的选项保持零值
+type cliArgs struct {
+	Game             string
+	Level            string
+	Env              string
+	Mode             string
+	Sr               int
+	Fb               bool
+	Seed             string
+	OnConflict       string
+	Atomic           string
+	DryRun           bool
+	Yes              bool
+	AllowDestructive bool
+	NoConnect        bool
+	Force            bool
+	Positional       []string        // 位置参数（旧用法或子命令）
+	set              map[string]bool // 命令行中显式指定的选项
+}
+
+// cliFlags 选项定义，命令按名称声明自己支持的选项，帮助信息由 flag 包生成
+var cliFlags = map[string]func(fs *flag.FlagSet, a *cliArgs){
+	"game": func(fs *flag.FlagSet, a *cliArgs) {
+		fs.StringVar(&a.Game, "game", "", "游戏ID，默认使用 config.yaml 中的 game.id")
+	},
+	"games": func(fs *flag.FlagSet, a *cliArgs) {
+		fs.StringVar(&a.Game, "game", "", "游戏ID，多个用逗号分隔，如 112,103,105（必填）")
+	},
+	"level": func(fs *flag.FlagSet, a *cliArgs) {
+		fs.StringVar(&a.Level, "level", "", "只处理指定RTP档位")
+	},
+	"env": func(fs *flag.FlagSet, a *cliArgs) {
+		fs.StringVar(&a.Env, "env", "", "数据库环境，默认使用 default_env（"+envCodesHelp()+"）")
+	},
+	"envs": func(fs *flag.FlagSet, a *cliArgs) {
+		fs.StringVar(&a.Env, "env", "", "只检查指定环境，多个用逗号分隔")
+	},
+	"mode": func(fs *flag.FlagSet, a *cliArgs) {
+		fs.StringVar(&a.Mode, "mode", "", "生成模式（"+strings.Join(StrategyNames(), "/")+"）")
+	},
+	"sr": func(fs *flag.FlagSet, a *cliArgs) {
+		fs.IntVar(&a.Sr, "sr", 0, "第几次生成（srNumber）")
+	},
+	"fb": func(fs *flag.FlagSet, a *cliArgs) {
+		fs.BoolVar(&a.Fb, "fb", false, "处理 <output-dir>/<gameId>_fb 下的购买夺宝文件")
+	},
+	"seed": func(fs *flag.FlagSet, a *cliArgs) {
+		fs.StringVar(&a.Seed, "seed", "", "基准种子，未指定时使用当前时间并打印")
+	},
+	"on-conflict": func(fs *flag.FlagSet, a *cliArgs) {
+		fs.StringVar(&a.OnConflict, "on-conflict", "", "切片已存在时的处理：fail（默认）/skip-existing/replace")
+	},
+	"atomic": func(fs *flag.FlagSet, a *cliArgs) {
+		fs.StringVar(&a.Atomic, "atomic", "", "事务范围：file（默认，每个文件一个事务）/game（整个游戏目录一个事务）")
+	},
+	"dry-run": func(fs *flag.FlagSet, a *cliArgs) {
+		fs.BoolVar(&a.DryRun, "dry-run", false, "只输出导入计划，不写入任何数据")
+	},
+	"yes": func(fs *flag.FlagSet, a *cliArgs) {
+		fs.BoolVar(&a.Yes, "yes", false, "写入受保护环境时跳过交互确认")
+	},
+	"allow-destructive": func(fs *flag.FlagSet, a *cliArgs) {
+		fs.BoolVar(&a.AllowDestructive, "allow-destructive", false, "允许在受保护环境上执行破坏性操作（如 --on-conflict replace）")
+	},
+	"no-connect": func(fs *flag.FlagSet, a *cliArgs) {
+		fs.BoolVar(&a.NoConnect, "no-connect", false, "只校验配置项，不连接数据库")
+	},
+	"upload-dry-run": func(fs *flag.FlagSet, a *cliArgs) {
+		fs.BoolVar(&a.DryRun, "dry-run", false, "只输出上传计划（与S3比对校验和），不上传任何文件")
+	},
+	"force": func(fs *flag.FlagSet, a *cliArgs) {
+		fs.BoolVar(&a.Force, "force", false, "不比对校验和，总是重新上传")
+	},
+}
+
+// importFlags 所有导入命令共用的选项
+var importFlags = []string{"level", "env", "on-conflict", "atomic", "dry-run", "yes", "allow-destructive"}
+
+// cliCommand 子命令定义
+type cliCommand struct {
+	Name    string
+	Summary string
+	Usage   string   // 参数用法，如 "--game <id> [--env <env>]"
+	Legacy  string   // 已弃用的位置参数用法，为空表示不接受位置参数
+	Flags   []string // 支持的选项（见 cliFlags）
+	Notes   []string // 帮助中选项之后的补充说明
+	Run     func(a *cliArgs)
+}
+
+// cliCommands 按帮助信息中的顺序返回全部子命令，生成命令由已注册的选择策略派生
+func cliCommands() []cliCommand {
+	var commands []cliCommand
+	for _, name := range StrategyNames() {
+		strategy, _ := LookupStrategy(name)
+		name := name
+		commands = append(commands, cliCommand{
+			Name:    name,
+			Summary: strategy.Description(),
+			Usage:   "[--game <id>] [--seed <N>]",
+			Flags:   []string{"game", "seed"},
+			Run: func(a *cliArgs) {
+				runGenerateMode(name, optionalGameID(a), mustBaseSeed(a))
+			},
+		})
+	}
+	return append(commands,
+		cliCommand{
+			Name:    "multi-game",
+			Summary: "多游戏顺序生成模式",
+			Usage:   "[--mode <mode>] [--seed <N>]",
+			Legacy:  "multi-game [mode]",
+			Flags:   []string{"mode", "seed"},
+			Run:     runMultiGameCommand,
+		},
+		cliCommand{
+			Name:    "regenerate",
+			Summary: "按文件记录的种子重建单个文件，并与原文件逐字节比对",
+			Usage:   "--game <id> --level <n> --sr <n> [--fb] [--mode <mode>] [--seed <N>]",
+			Legacy:  "regenerate <gameId> <level> <srNumber>",
+			Flags:   []string{"game", "level", "sr", "fb", "mode", "seed"},
+			Run:     runRegenerateCommand,
+		},
+		cliCommand{
+			Name:    "verify",
+			Summary: "校验生成目录：条数、RTP区间、奖项数量、重复使用",
+			Usage:   "--game <id> [--fb]",
+			Legacy:  "verify <gameId>",
+			Flags:   []string{"game", "fb"},
+			Run: func(a *cliArgs) {
+				a.legacyPositional("verify", "game")
+				runVerifyMode(requiredGameID(a), a.Fb)
+			},
+		},
+		cliCommand{
+			Name:    "verify-db",
+			Summary: "审计已导入的目标表：RTP、条数、srId连续性、srNumber覆盖",
+			Usage:   "--game <id> [--env <env>] [--mode <mode>]",
+			Legacy:  "verify-db <gameId> [env]",
+			Flags:   []string{"game", "env", "mode"},
+			Run: func(a *cliArgs) {
+				a.legacyPositional("verify-db", "game", "env")
+				runVerifyDbMode(requiredGameID(a), a.mustEnv(), a.Mode)
+			},
+		},
+		cliCommand{
+			Name:    "config",
+			Summary: "config check：校验配置项（带YAML路径）并测试各环境连通性",
+			Usage:   "check [--env <e1,e2>] [--no-connect]",
+			Legacy:  "config check [env...]",
+			Flags:   []string{"envs", "no-connect"},
+			Run:     runConfigCommand,
+		},
+		cliCommand{
+			Name:    "import",
+			Summary: "导入 <output-dir>/<gameId> 下的JSON文件到数据库",
+			Usage:   "[--game <id>] [--level <n>] [--env <env>] [--on-conflict <p>] [--atomic <s>] [--dry-run] [--yes] [--allow-destructive]",
+			Legacy:  "import [gameId|levelId] [level] [env]",
+			Flags:   append([]string{"game"}, importFlags...),
+			Run:     func(a *cliArgs) { runLocalImportCommand(a, false) },
+		},
+		cliCommand{
+			Name:    "importFb",
+			Summary: "导入 <output-dir>/<gameId>_fb 下的购买夺宝JSON文件（rtpLevel 写入为 档位+0.1）",
+			Usage:   "[--game <id>] [--level <n>] [--env <env>] [--on-conflict <p>] [--atomic <s>] [--dry-run] [--yes] [--allow-destructive]",
+			Legacy:  "importFb [gameId|levelId] [level] [env]",
+			Flags:   append([]string{"game"}, importFlags...),
+			Run:     func(a *cliArgs) { runLocalImportCommand(a, true) },
+		},
+		s3ImportCommand("import-s3", "auto", "从S3智能导入（自动检测normal和fb模式，先normal后fb）"),
+		s3ImportCommand("import-s3-normal", "normal", "从S3导入普通模式文件"),
+		s3ImportCommand("import-s3-fb", "fb", "从S3导入购买夺宝模式文件"),
+		cliCommand{
+			Name:    "upload-s3",
+			Summary: "上传 <output-dir>/<gameId>[_fb] 到 import-s3 读取的S3前缀（未变化的文件跳过）",
+			Usage:   "--game <ids> [--level <n>] [--dry-run] [--force]",
+			Flags:   []string{"games", "level", "upload-dry-run", "force"},
+			Run:     runS3UploadCommand,
+		},
+	)
+}
+
+// s3ImportCommand S3导入命令，三种模式共用同一组选项
+func s3ImportCommand(name, mode, summary string) cliCommand {
+	return cliCommand{
+		Name:    name,
+		Summary: summary,
+		Usage:   "--game <ids> [--level <n>] [--env <env>] [--on-conflict <p>] [--atomic <s>] [--dry-run] [--yes] [--allow-destructive]",
+		Legacy:  name + " <gameIds> [level] [env]",
+		Flags:   append([]string{"games"}, importFlags...),
+		Notes: []string{
+			"目标为受保护环境（且未加 --dry-run）时先以 dry-run 生成写入计划，确认后再写入：",
+			"每个S3对象会被下载并解析两次（生成计划一次、写入一次），下载量与耗时约为普通导入的两倍",
+		},
+		Run: func(a *cliArgs) { runS3ImportCommand(a, name, mode) },
+	}
+}
+
+// lookupCommand 按名称查找子命令
+func lookupCommand(name string) (cliCommand, bool) {
+	for _, cmd := range cliCommands() {
+		if cmd.Name == name {
+			return cmd, true
+		}
+	}
+	return cliCommand{}, false
+}
+
+// newFlagSet 创建子命令的选项集合，包含全局选项 --config 与 --output-dir
+func newFlagSet(cmd cliCommand, a *cliArgs) *flag.FlagSet {
+	fs := flag.NewFlagSet(cmd.Name, flag.ContinueOnError)
+	fs.SetOutput(os.Stdout)
+	for _, name := range cmd.Flags {
+		cliFlags[name](fs, a)
+	}
+	addGlobalFlags(fs)
+	fs.Usage = func() { printCommandHelp(cmd, fs) }
+	return fs
+}
+
+// addGlobalFlags 所有命令都支持的全局选项
+func addGlobalFlags(fs *flag.FlagSet) {
+	fs.StringVar(&configPath, "config", configPath, "配置文件路径")
+	fs.StringVar(&outputRoot, "output-dir", outputRoot, "生成结果根目录")
+}
+
+// parseInterspersed 解析选项，允许选项与位置参数交错出现（flag 包默认遇到第一个位置参数即停止）
+func parseInterspersed(fs *flag.FlagSet, args []string) ([]string, error) {
+	var positional []string
+	for {
+		if err := fs.Parse(args); err != nil {
+			return nil, err
+		}
+		args = fs.Args()
+		if len(args) == 0 {
+			return positional, nil
+		}
+		positional = append(positional, args[0])
+		args = args[1:]
+	}
+}
+
+// printCommandHelp 输出单个子命令的帮助
+func printCommandHelp(cmd cliCommand, fs *flag.FlagSet) {
+	fmt.Printf("%s\n\n用法: ./filteringData %s %s\n", cmd.Summary, cmd.Name, cmd.Usage)
+	if cmd.Legacy != "" {
+		fmt.Printf("旧用法（已弃用）: ./filteringData %s\n", cmd.Legacy)
+	}
+	fmt.Println("\n选项:")
+	fs.PrintDefaults()
+	if len(cmd.Notes) > 0 {
+		fmt.Println("\n说明:")
+		for _, note := range cmd.Notes {
+			fmt.Printf("  %s\n", note)
+		}
+	}
+}
+
+// printUsage 输出全部子命令列表
+func printUsage() {
+	fmt.Println("使用方法: ./filteringData [--config <file>] [--output-dir <dir>] <command> [选项]")
+	fmt.Println("\n命令:")
+	for _, cmd := range cliCommands() {
+		fmt.Printf("  %-17s %s\n", cmd.Name, cmd.Summary)
+	}
+	fmt.Println("\n使用 ./filteringData help <command> 或 ./filteringData <command> --help 查看命令选项")
+	fmt.Println("\n示例:")
+	fmt.Println("  ./filteringData generate --seed 20240101              # 使用固定种子生成")
+	fmt.Println("  ./filteringData multi-game --mode generate2           # 多游戏顺序生成")
+	fmt.Println("  ./filteringData regenerate --game 93 --level 5 --sr 3 # 重建 output/93/GameResults_5_3.json 并与原文件比对")
+	fmt.Println("  ./filteringData verify --game 93 --fb                 # 校验 output/93_fb 下的全部文件")
+	fmt.Println("  ./filteringData verify-db --game 93 --env hp          # 审计生产环境 GameResults_93 表")
+	fmt.Println("  ./filteringData config check                          # 检查配置文件与所有已配置环境")
+	fmt.Println("  ./filteringData import --game 93 --env bt             # 导入 output/93 到巴西测试环境")
+	fmt.Println("  ./filteringData import --level 5                      # 导入 output/<game.id> 下档位 5 的文件")
+	fmt.Println("  ./filteringData import --game 93 --on-conflict replace # 重新导入并替换已有切片")
+	fmt.Println("  ./filteringData import-s3 --game 112,103 --level 50 --env hp --dry-run # 预览导入生产环境的计划")
+	fmt.Println("  ./filteringData upload-s3 --game 112,103              # 上传 output/112、output/112_fb 等目录到S3")
+}
+
+// runCLI 解析命令行并执行子命令
+func runCLI(argv []string) {
+	// 命令名之前的全局选项
+	global := flag.NewFlagSet("filteringData", flag.ContinueOnError)
+	global.SetOutput(os.Stdout)
+	addGlobalFlags(global)
+	global.Usage = printUsage
+	if err := global.Parse(argv); err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			os.Exit(0)
+		}
+		os.Exit(2)
+	}
+	rest := global.Args()
+
+	// 环境别名可在配置文件中声明，需在输出帮助与解析参数前登记
+	preloadEnvironments(configPath)
+
+	if len(rest) == 0 {
+		printUsage()
+		os.Exit(1)
+	}
+	name, args := rest[0], rest[1:]
+	if name == "help" {
+		if len(args) == 0 {
+			printUsage()
+			return
+		}
+		name, args = args[0], []string{"--help"}
+	}
+
+	cmd, ok := lookupCommand(name)
+	if !ok {
+		fmt.Printf("未知命令: %s\n\n", name)
+		printUsage()
+		os.Exit(1)
+	}
+
+	a := &cliArgs{set: make(map[string]bool)}
+	fs := newFlagSet(cmd, a)
+	positional, err := parseInterspersed(fs, args)
+	if err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			os.Exit(0)
+		}
+		os.Exit(2)
+	}
+	fs.Visit(func(f *flag.Flag) { a.set[f.Name] = true })
+	// --config 出现在命令名之后时重新登记环境
+	if a.set["config"] {
+		preloadEnvironments(configPath)
+	}
+	a.Positional = positional
+	if cmd.Legacy == "" && len(positional) > 0 {
+		fmt.Printf("❌ %s 不接受位置参数: %s\n\n", cmd.Name, strings.Join(positional, " "))
+		printCommandHelp(cmd, fs)
+		os.Exit(2)
+	}
+	cmd.Run(a)
+}
+
+// cliFail 参数错误时输出提示并退出
+func cliFail(format string, a ...interface{}) {
+	fmt.Printf("❌ 参数错误: "+format+"\n", a...)
+	os.Exit(2)
+}
+
+// warnDeprecated 旧的位置参数用法提示，并给出等价的新写法
+func warnDeprecated(command string, a *cliArgs) {
+	fmt.Printf("⚠️ 位置参数用法已弃用，后续版本将移除，请改用: ./filteringData %s%s\n", command, a.flagString())
+}
+
+// flagString 已确定的选项对应的新写法
+func (a *cliArgs) flagString() string {
+	var b strings.Builder
+	add := func(name, value string) {
+		if value != "" {
+			fmt.Fprintf(&b, " --%s %s", name, value)
+		}
+	}
+	add("game", a.Game)
+	add("level", a.Level)
+	if a.Sr > 0 {
+		add("sr", strconv.Itoa(a.Sr))
+	}
+	add("env", a.Env)
+	add("mode", a.Mode)
+	if a.Fb {
+		b.WriteString(" --fb")
+	}
+	return b.String()
+}
+
+// legacyPositional 按固定顺序把旧的位置参数映射到选项（game、level、sr、env、mode），已显式指定的选项不允许再用位置参数
+func (a *cliArgs) legacyPositional(command string, names ...string) {
+	if len(a.Positional) == 0 {
+		return
+	}
+	if len(a.Positional) > len(names) {
+		cliFail("%s 位置参数过多: %s", command, strings.Join(a.Positional, " "))
+	}
+	for i, value := range a.Positional {
+		name := names[i]
+		if a.set[name] {
+			cliFail("--%s 与位置参数 %s 重复", name, value)
+		}
+		switch name {
+		case "game":
+			a.Game = value
+		case "level":
+			a.Level = value
+		case "sr":
+			sr, err := strconv.Atoi(value)
+			if err != nil {
+				cliFail("srNumber 必须为数字: %s", value)
+			}
+			a.Sr = sr
+		case "env":
+			a.Env = value
+		case "mode":
+			a.Mode = value
+		}
+		a.set[name] = true
+	}
+	warnDeprecated(command, a)
+}
+
+// optionalGameID --game 的值，未指定时返回 0（使用配置中的 game.id）
+func optionalGameID(a *cliArgs) int {
+	if a.Game == "" {
+		return 0
+	}
+	gameID, err := strconv.Atoi(a.Game)
+	if err != nil || gameID <= 0 {
+		cliFail("--game 必须为正整数: %s", a.Game)
+	}
+	return gameID
+}
+
+// requiredGameID 必填的 --game
+func requiredGameID(a *cliArgs) int {
+	if a.Game == "" {
+		cliFail("缺少 --game <id>")
+	}
+	return optionalGameID(a)
+}
+
+// mustEnv --env 解析为完整环境名，未指定时返回空（使用 default_env）
+func (a *cliArgs) mustEnv() string {
+	if a.Env == "" {
+		return ""
+	}
+	if !IsEnv(a.Env) {
+		cliFail("无效的环境: %s（支持: %s）", a.Env, envCodesHelp())
+	}
+	return ResolveEnv(a.Env)
+}
+
+// writeGuard 受保护环境的写入确认选项
+func (a *cliArgs) writeGuard() writeGuard {
+	return writeGuard{Yes: a.Yes, AllowDestructive: a.AllowDestructive}
+}
+
+// mustBaseSeed 解析 --seed，解析失败直接退出
+func mustBaseSeed(a *cliArgs) int64 {
+	seed, err := resolveBaseSeed(a.Seed, a.set["seed"])
+	if err != nil {
+		fmt.Printf("❌ %v\n", err)
+		os.Exit(1)
+	}
+	return seed
+}
+
+// runMultiGameCommand multi-game [--mode m] [--seed N]
+func runMultiGameCommand(a *cliArgs) {
+	a.legacyPositional("multi-game", "mode")
+	mode := a.Mode
+	if mode == "" {
+		mode = "generate" // 默认模式
+	}
+	runMultiGameMode(mode, mustBaseSeed(a))
+}
+
+// runRegenerateCommand regenerate --game <id> --level <n> --sr <n> [--fb] [--mode m] [--seed N]
+func runRegenerateCommand(a *cliArgs) {
+	a.legacyPositional("regenerate", "game", "level", "sr")
+	gameID := requiredGameID(a)
+	if a.Level == "" || a.Sr <= 0 {
+		cliFail("缺少 --level <n> 或 --sr <n>")
+	}
+	rtpNo, err := strconv.ParseFloat(a.Level, 64)
+	if err != nil {
+		cliFail("--level 必须为数字: %s", a.Level)
+	}
+	runRegenerateMode(gameID, rtpNo, a.Sr, a.Fb, a.Mode, a.Seed, a.set["seed"])
+}
+
+// runConfigCommand config check [--env e1,e2] [--no-connect]
+func runConfigCommand(a *cliArgs) {
+	if len(a.Positional) == 0 || a.Positional[0] != "check" {
+		cliFail("用法: ./filteringData config check [--env <e1,e2>] [--no-connect]")
+	}
+	var envs []string
+	if a.Env != "" {
+		envs = strings.Split(a.Env, ",")
+	}
+	if legacy := a.Positional[1:]; len(legacy) > 0 {
+		if a.set["env"] {
+			cliFail("--env 与位置参数 %s 重复", strings.Join(legacy, " "))
+		}
+		envs = legacy
+		fmt.Printf("⚠️ 位置参数用法已弃用，后续版本将移除，请改用: ./filteringData config check --env %s\n", strings.Join(legacy, ","))
+	}
+	runConfigCheckMode(envs, a.NoConnect)
+}
+
+// legacyLocalImport 旧的 import/importFb 位置参数：按 <output-dir>/<arg>[_fb] 目录是否存在判断 gameId 还是 levelId
+// 目录名与档位号相同时会导入错误的数据，因此仅为兼容保留
+func legacyLocalImport(a *cliArgs, command string, fb bool) {
+	args := a.Positional
+	if len(args) == 0 {
+		return
+	}
+	if a.set["game"] || a.set["level"] || a.set["env"] {
+		cliFail("--game/--level/--env 不能与位置参数混用")
+	}
+	isGame := isGameId
+	if fb {
+		isGame = isGameIdFb
+	}
+	switch len(args) {
+	case 1:
+		if isGame(args[0]) {
+			a.Game = args[0]
+		} else {
+			a.Level = args[0]
+		}
+	case 2:
+		arg1, arg2 := args[0], args[1]
+		if isGame(arg1) && IsEnv(arg2) {
+			a.Game, a.Env = arg1, arg2
+		} else if IsEnv(arg2) {
+			a.Level, a.Env = arg1, arg2
+		} else if isGame(arg1) {
+			a.Game, a.Level = arg1, arg2
+		} else {
+			cliFail("无法识别参数组合: %s", strings.Join(args, " "))
+		}
+	case 3:
+		a.Game, a.Level, a.Env = args[0], args[1], args[2]
+	default:
+		cliFail("%s 位置参数过多: %s", command, strings.Join(args, " "))
+	}
+	warnDeprecated(command, a)
+}
+
+// runLocalImportCommand import/importFb：指定 --game 时导入该游戏目录，否则导入 config.yaml 中 game.id 的目录
+func runLocalImportCommand(a *cliArgs, fb bool) {
+	command := "import"
+	if fb {
+		command = "importFb"
+	}
+	legacyLocalImport(a, command, fb)
+	options := mustImportOptions(a)
+	gameID := optionalGameID(a)
+	env := a.mustEnv()
+
+	runGuardedImport(env, options, a.writeGuard(), func(options ImportOptions) {
+		switch {
+		case fb && gameID > 0:
+			runImportFbModeWithGameId(gameID, a.Level, env, options)
+		case fb:
+			runImportFbMode(a.Level, env, options)
+		case gameID > 0:
+			runImportModeWithGameId(gameID, a.Level, env, options)
+		default:
+			runImportMode(a.Level, env, options)
+		}
+	})
+}
+
+// runS3ImportCommand import-s3/import-s3-normal/import-s3-fb --game <ids> [--level n] [--env e]
+func runS3ImportCommand(a *cliArgs, command, mode string) {
+	if args := a.Positional; len(args) > 0 {
+		if a.set["game"] || a.set["level"] || a.set["env"] {
+			cliFail("--game/--level/--env 不能与位置参数混用")
+		}
+		if len(args) > 3 {
+			cliFail("%s 位置参数过多: %s", command, strings.Join(args, " "))
+		}
+		a.Game = args[0]
+		// 第二个参数可能是环境或等级
+		if len(args) > 1 {
+			if IsEnv(args[1]) && len(args) == 2 {
+				a.Env = args[1]
+			} else {
+				a.Level = args[1]
+			}
+		}
+		if len(args) > 2 {
+			a.Env = args[2]
+		}
+		warnDeprecated(command, a)
+	}
+	if a.Game == "" {
+		cliFail("缺少 --game <ids>，如 --game 112,103,105")
+	}
+	gameIds, err := parseGameIds(a.Game)
+	if err != nil {
+		cliFail("解析游戏ID失败: %v", err)
+	}
+	env := a.mustEnv()
+	runGuardedImport(env, mustImportOptions(a), a.writeGuard(), func(options ImportOptions) {
+		runS3ImportMode(gameIds, mode, a.Level, env, options)
+	})
+}
+
+// runS3UploadCommand upload-s3 --game <ids> [--level n] [--dry-run] [--force]
+func runS3UploadCommand(a *cliArgs) {
+	if a.Game == "" {
+		cliFail("缺少 --game <ids>，如 --game 112,103,105")
+	}
+	gameIds, err := parseGameIds(a.Game)
+	if err != nil {
+		cliFail("解析游戏ID失败: %v", err)
+	}
+	if a.Level != "" {
+		if _, err := strconv.Atoi(a.Level); err != nil {
+			cliFail("--level 必须为整数: %s", a.Level)
+		}
+	}
+	runS3UploadMode(gameIds, a.Level, a.Force, a.DryRun)
+}
@@ -5,47 +5,48 @@ import (
 	"fmt"
 	"io/ioutil"
 	"os"
-	"strconv"
 	"strings"
 
 	"gopkg.in/yaml.v3"
 )
 
-// 环境映射表
-var envMapping = map[string]string{
-	// 完整名称
-	"local":   "local",
-	"hk-test": "hk-test",
-	"br-test": "br-test",
-	"br-prod": "br-prod",
-	"us-prod": "us-prod",
-	"hk-prod": "hk-prod",
-
-	// 简短别名
-	"l":  "local",
-	"ht": "hk-test",
-	"bt": "br-test",
-	"bp": "br-prod",
-	"up": "us-prod",
-	"hp": "hk-prod",
-}
-
 // DatabaseConfig 数据库配置结构体
+// 同时用于 environments 中的环境声明：aliases 为命令行可用的别名，credentials 为凭据来源
 type DatabaseConfig struct {
-	Host     string `yaml:"host"`
-	Port     int    `yaml:"port"`
-	User     string `yaml:"user"`
-	Password string `yaml:"password"`
-	Dbname   string `yaml:"dbname"`
-	SSLMode  string `yaml:"sslmode"`
-	Timezone string `yaml:"timezone"`
+	Host        string   `yaml:"host"`
+	Port        int      `yaml:"port"`
+	User        string   `yaml:"user"`
+	Password    string   `yaml:"password"`
+	Dbname      string   `yaml:"dbname"`
+	SSLMode     string   `yaml:"sslmode"`
+	Timezone    string   `yaml:"timezone"`
+	Aliases     []string `yaml:"aliases"`
+	Credentials string   `yaml:"credentials"` // config（默认）或 env
+	EnvPrefix   string   `yaml:"env_prefix"`  // credentials 为 env 时的环境变量前缀，如 HT → HT_DB_HOST
+	Protected   *bool    `yaml:"protected"`   // 受保护环境：写入前需确认，禁止破坏性操作；未填写时沿用内置值
+
+	declared bool // 是否在配置文件中声明（否则为内置默认环境）
+}
+
+// DSN 生成 lib/pq 连接串，未配置的 sslmode/TimeZone 不写入，使用驱动默认值
+func (d *DatabaseConfig) DSN() string {
+	dsn := fmt.Sprintf("host=%s port=%d user=%s password=%s dbname=%s", d.Host, d.Port, d.User, d.Password, d.Dbname)
+	if d.SSLMode != "" {
+		dsn += " sslmode=" + d.SSLMode
+	}
+	if d.Timezone != "" {
+		dsn += " TimeZone=" + d.Timezone
+	}
+	return dsn
 }
 
 // GameConfig 单个游戏配置结构体
 type GameConfig struct {
-	ID   int     `yaml:"id"`   // 游戏ID
-	BL   float64 `yaml:"bl"`   // 投注线数
-	IsFb bool    `yaml:"isFb"` // 是否启用购买夺宝
+	ID          int            `yaml:"id"`          // 游戏ID
+	BL          float64        `yaml:"bl"`          // 投注线数
+	IsFb        bool           `yaml:"isFb"`        // 是否启用购买夺宝
+	RtpLevels   RtpLevelTables `yaml:"rtp_levels"`  // 该游戏覆盖的档位表（按表整体替换）
+	Concurrency int            `yaml:"concurrency"` // 该游戏同时运行的生成任务数上限（0 表示使用 settings.generation.per_game_concurrency）
 }
 
 // Config 配置结构体
@@ -105,6 +106,9 @@ type Config struct {
 		SpecialGameplay float64 `yaml:"special_gameplay"`
 	} `yaml:"rtp"`
 
+	// 档位表（未配置时使用 rtp_levels.go 中的内置默认值）
+	RtpLevels RtpLevelTables `yaml:"rtp_levels"`
+
 	StageRatios struct {
 		Stage1MinRatio    float64 `yaml:"stage1_min_ratio"`
 		Stage1MaxRatio    float64 `yaml:"stage1_max_ratio"`
@@ -118,10 +122,26 @@ type Config struct {
 		Timeout   int    `yaml:"timeout"`
 		// S3导入优化配置
 		S3Import struct {
-			MaxConcurrency int `yaml:"max_concurrency"` // 最大并发数
-			BatchSize      int `yaml:"batch_size"`      // 批处理大小
-			BufferSize     int `yaml:"buffer_size"`     // 缓冲区大小
+			MaxConcurrency int `yaml:"max_concurrency"`  // 最大并发数
+			BatchSize      int `yaml:"batch_size"`       // 批处理大小
+			BufferSize     int `yaml:"buffer_size"`      // 缓冲区大小
+			RetryAttempts  int `yaml:"retry_attempts"`   // 下载中断时连续重试的次数（0 表示 5），读到新数据后重新计数
+			RetryBackoffMs int `yaml:"retry_backoff_ms"` // 首次重试前的等待毫秒数，之后每次翻倍，最长 30 秒（0 表示 500）
 		} `yaml:"s3_import"`
+		// S3上传配置
+		S3Upload struct {
+			MaxConcurrency int `yaml:"max_concurrency"` // 同时上传的文件数（0 表示 4）
+			PartSizeMB     int `yaml:"part_size_mb"`    // 分片大小(MB)，超过该大小的文件使用分片上传（0 表示 64，最小 5）
+		} `yaml:"s3_upload"`
+		// 生成结果输出配置
+		Output struct {
+			Gzip bool `yaml:"gzip"` // 生成 GameResults_<level>_<n>.json.gz
+		} `yaml:"output"`
+		// 生成任务调度配置
+		Generation struct {
+			MaxConcurrency     int `yaml:"max_concurrency"`      // 所有游戏共用的并发任务数（0 表示 CPU 核数）
+			PerGameConcurrency int `yaml:"per_game_concurrency"` // 单个游戏的并发任务数上限（0 表示不单独限制）
+		} `yaml:"generation"`
 		// 数据库连接池配置
 		Database struct {
 			MaxOpenConns    int `yaml:"max_open_conns"`     // 最大打开连接数
@@ -134,117 +154,50 @@ type Config struct {
 
 	// S3配置
 	S3 struct {
-		Enabled         bool   `yaml:"enabled"`
-		Bucket          string `yaml:"bucket"`
-		Region          string `yaml:"region"`
-		AccessKeyID     string `yaml:"access_key_id"`
-		SecretAccessKey string `yaml:"secret_access_key"`
-		NormalPrefix    string `yaml:"normal_prefix"` // 普通模式路径前缀
-		FbPrefix        string `yaml:"fb_prefix"`     // 购买夺宝模式路径前缀
+		Enabled              bool   `yaml:"enabled"`
+		Bucket               string `yaml:"bucket"`
+		Region               string `yaml:"region"`
+		Credentials          string `yaml:"credentials"`             // 凭据来源：static/profile/default/web_identity，为空时有静态密钥用 static，否则用 default
+		AccessKeyID          string `yaml:"access_key_id"`           // static：也可通过环境变量 AWS_ACCESS_KEY_ID 提供
+		SecretAccessKey      string `yaml:"secret_access_key"`       // static：也可通过环境变量 AWS_SECRET_ACCESS_KEY 提供
+		Profile              string `yaml:"profile"`                 // profile：~/.aws/config 中的 profile 名（支持 SSO、assume role）
+		RoleARN              string `yaml:"role_arn"`                // web_identity：要扮演的角色，为空时读取 AWS_ROLE_ARN
+		WebIdentityTokenFile string `yaml:"web_identity_token_file"` // web_identity：OIDC 令牌文件，为空时读取 AWS_WEB_IDENTITY_TOKEN_FILE
+		RoleSessionName      string `yaml:"role_session_name"`       // web_identity：会话名，为空时读取 AWS_ROLE_SESSION_NAME
+		NormalPrefix         string `yaml:"normal_prefix"`           // 普通模式路径前缀模板，{gameId} 替换为游戏ID，默认 mpg-slot-data/{gameId}/normal/
+		FbPrefix             string `yaml:"fb_prefix"`               // 购买夺宝模式路径前缀模板，默认 mpg-slot-data/{gameId}/fb/
+		Endpoint             string `yaml:"endpoint"`                // 自定义 endpoint（MinIO、本地 S3 或私有镜像），为空时使用 AWS
+		UsePathStyle         bool   `yaml:"use_path_style"`          // 使用 path-style 地址（<endpoint>/<bucket>/<key>），MinIO 通常需要开启
 	} `yaml:"s3"`
 }
 
-// ResolveEnv 解析环境参数
-func ResolveEnv(envArg string) string {
-	if fullEnv, exists := envMapping[envArg]; exists {
-		return fullEnv
-	}
-	return envArg // 如果不在映射表中，直接返回原值
-}
-
-// IsEnv 检查参数是否为环境代码
-func IsEnv(arg string) bool {
-	_, exists := envMapping[arg]
-	return exists
-}
-
-// GetDatabaseConfig 根据环境获取数据库配置
-func (c *Config) GetDatabaseConfig(env string) (*DatabaseConfig, error) {
-	if env == "" {
-		env = c.DefaultEnv
-	}
-
-	// 解析环境参数（支持简写）
-	env = ResolveEnv(env)
-
-	// 如果是 local 环境，使用配置文件中的配置
-	if env == "local" {
-		if dbConfig, exists := c.Environments[env]; exists {
-			return &dbConfig, nil
+// FindGame 在 multi_game.games 中查找指定游戏的配置
+func (c *Config) FindGame(gameID int) (GameConfig, bool) {
+	for _, game := range c.MultiGame.Games {
+		if game.ID == gameID {
+			return game, true
 		}
-		// 向后兼容：如果environments为空，使用传统的database配置
-		if len(c.Environments) == 0 {
-			return &c.Database.Postgres, nil
-		}
-	}
-
-	// 对于其他环境，直接从环境变量读取
-	dbConfig, err := getDatabaseConfigFromEnv(env)
-	if err != nil {
-		return nil, err
 	}
-
-	return dbConfig, nil
+	return GameConfig{}, false
 }
 
-// getDatabaseConfigFromEnv 从环境变量读取数据库配置
-func getDatabaseConfigFromEnv(env string) (*DatabaseConfig, error) {
-	// 根据环境确定环境变量前缀
-	var prefix string
-	switch env {
-	case "hk-test":
-		prefix = "HT"
-	case "br-test":
-		prefix = "BT"
-	case "br-prod":
-		prefix = "BP"
-	case "us-prod":
-		prefix = "UP"
-	case "hk-prod":
-		prefix = "HP"
-	default:
-		return nil, fmt.Errorf("不支持的环境: %s", env)
-	}
-
-	// 从环境变量读取配置
-	host := os.Getenv(prefix + "_DB_HOST")
-	portStr := os.Getenv(prefix + "_DB_PORT")
-	user := os.Getenv(prefix + "_DB_USER")
-	password := os.Getenv(prefix + "_DB_PASSWORD")
-	dbname := os.Getenv(prefix + "_DB_NAME")
-
-	// 检查必需的环境变量
-	if host == "" {
-		return nil, fmt.Errorf("环境变量 %s_DB_HOST 未设置", prefix)
-	}
-	if portStr == "" {
-		return nil, fmt.Errorf("环境变量 %s_DB_PORT 未设置", prefix)
-	}
-	if user == "" {
-		return nil, fmt.Errorf("环境变量 %s_DB_USER 未设置", prefix)
-	}
-	if password == "" {
-		return nil, fmt.Errorf("环境变量 %s_DB_PASSWORD 未设置", prefix)
-	}
-	if dbname == "" {
-		return nil, fmt.Errorf("环境变量 %s_DB_NAME 未设置", prefix)
-	}
+// ForGame 基于当前配置生成指定游戏的配置副本（覆盖游戏ID、投注线数和购买夺宝开关）
+func (c *Config) ForGame(game GameConfig) *Config {
+	gameConfig := *c
+	gameConfig.Game.ID = game.ID
+	gameConfig.Game.IsFb = game.IsFb
+	gameConfig.Bet.BL = game.BL
+	return &gameConfig
+}
 
-	// 解析端口号
-	port, err := strconv.Atoi(portStr)
-	if err != nil {
-		return nil, fmt.Errorf("环境变量 %s_DB_PORT 不是有效的整数: %s", prefix, portStr)
+// ForGameID 指定游戏的配置：multi_game.games 中有该游戏时使用其配置，否则只覆盖游戏ID
+func (c *Config) ForGameID(gameID int) *Config {
+	if game, ok := c.FindGame(gameID); ok {
+		return c.ForGame(game)
 	}
-
-	return &DatabaseConfig{
-		Host:     host,
-		Port:     port,
-		User:     user,
-		Password: password,
-		Dbname:   dbname,
-		SSLMode:  "require",
-		Timezone: "UTC",
-	}, nil
+	gameConfig := *c
+	gameConfig.Game.ID = gameID
+	return &gameConfig
 }
 
 // loadEnvFile 加载 .env 文件
@@ -281,8 +234,21 @@ func loadEnvFile(filename string) error {
 	return scanner.Err()
 }
 
-// LoadConfig 加载配置文件
+// LoadConfig 加载配置文件并做语义校验，任何命令都不会带着有问题的配置开始执行
 func LoadConfig(filename string) (*Config, error) {
+	config, err := readConfig(filename)
+	if err != nil {
+		return nil, err
+	}
+	if problems := config.Problems(); len(problems) > 0 {
+		return nil, fmt.Errorf("配置校验失败（共 %d 项，可运行 ./filteringData config check 查看）:\n  - %s",
+			len(problems), strings.Join(problems, "\n  - "))
+	}
+	return config, nil
+}
+
+// readConfig 读取并解析配置文件，不做语义校验
+func readConfig(filename string) (*Config, error) {
 	// 首先尝试加载 .env 文件
 	if err := loadEnvFile(".env"); err != nil {
 		return nil, fmt.Errorf("加载 .env 文件失败: %v", err)
@@ -303,6 +269,7 @@ func LoadConfig(filename string) (*Config, error) {
 	if config.DefaultEnv == "" {
 		config.DefaultEnv = "local"
 	}
+	config.applyEnvironmentDefaults()
 
 	return &config, nil
 }
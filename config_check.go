@@ -0,0 +1,280 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"net/url"
+	"os"
+	"strings"
+	"time"
+)
+
+// configPingTimeout config check 中单个环境的连通性测试超时
+const configPingTimeout = 5 * time.Second
+
+// Problems 对配置做语义校验，一次性返回全部问题，每项以出错键的 YAML 路径开头
+func (c *Config) Problems() []string {
+	var problems []string
+	addf := func(format string, a ...interface{}) {
+		problems = append(problems, fmt.Sprintf(format, a...))
+	}
+	positive := func(path string, v float64) {
+		if v <= 0 {
+			addf("%s: 必须大于 0，当前为 %g", path, v)
+		}
+	}
+	ratio := func(path string, v float64) {
+		if v < 0 || v > 1 {
+			addf("%s: 必须在 [0, 1] 内，当前为 %g", path, v)
+		}
+	}
+
+	fbEnabled := c.Game.IsFb
+	for _, game := range c.MultiGame.Games {
+		fbEnabled = fbEnabled || game.IsFb
+	}
+
+	// 环境
+	problems = append(problems, c.environmentProblems()...)
+
+	// 多游戏
+	if c.MultiGame.Enabled && len(c.MultiGame.Games) == 0 {
+		addf("multi_game.games: multi_game.enabled 为 true 时至少需要配置一个游戏")
+	}
+	seenGames := make(map[int]int)
+	for i, game := range c.MultiGame.Games {
+		if game.ID <= 0 {
+			addf("multi_game.games[%d].id: 必须大于 0，当前为 %d", i, game.ID)
+		} else if first, dup := seenGames[game.ID]; dup {
+			addf("multi_game.games[%d].id: 游戏 %d 与 multi_game.games[%d] 重复", i, game.ID, first)
+		} else {
+			seenGames[game.ID] = i
+		}
+		positive(fmt.Sprintf("multi_game.games[%d].bl", i), game.BL)
+		if game.Concurrency < 0 {
+			addf("multi_game.games[%d].concurrency: 不能为负数，当前为 %d", i, game.Concurrency)
+		}
+	}
+
+	// 表与数据量
+	if c.Tables.SourceTablePrefix == "" {
+		addf("tables.source_table_prefix: 不能为空")
+	}
+	if c.Tables.OutputTablePrefix == "" {
+		addf("tables.output_table_prefix: 不能为空")
+	}
+	positive("tables.data_num", float64(c.Tables.DataNum))
+	positive("tables.data_table_num", float64(c.Tables.DataTableNum))
+	if c.Tables.DataNumV3 < 0 {
+		addf("tables.data_num_v3: 不能为负数，当前为 %d", c.Tables.DataNumV3)
+	}
+	if c.Tables.DataTableNum3 < 0 {
+		addf("tables.data_table_num_3: 不能为负数，当前为 %d", c.Tables.DataTableNum3)
+	}
+	if fbEnabled {
+		positive("tables.data_num_fb", float64(c.Tables.DataNumFb))
+		positive("tables.data_table_num_fb", float64(c.Tables.DataTableNumFb))
+	}
+
+	// 投注
+	positive("bet.cs", c.Bet.CS)
+	positive("bet.ml", c.Bet.ML)
+	if !c.MultiGame.Enabled || len(c.MultiGame.Games) == 0 {
+		positive("bet.bl", c.Bet.BL)
+	}
+	if fbEnabled {
+		positive("bet.fb", c.Bet.FB)
+	}
+
+	// 比例
+	ratio("prize_ratios.big_prize", c.PrizeRatios.BigPrize)
+	ratio("prize_ratios.mega_prize", c.PrizeRatios.MegaPrize)
+	ratio("prize_ratios.super_mega_prize", c.PrizeRatios.SuperMegaPrize)
+	ratio("gameplay_ratios.normal_gameplay", c.GameplayRatios.NormalGameplay)
+	ratio("gameplay_ratios.special_gameplay", c.GameplayRatios.SpecialGameplay)
+	ratio("stage_ratios.stage1_min_ratio", c.StageRatios.Stage1MinRatio)
+	ratio("stage_ratios.stage1_max_ratio", c.StageRatios.Stage1MaxRatio)
+	ratio("stage_ratios.stage3_win_top_ratio", c.StageRatios.Stage3WinTopRatio)
+	if c.StageRatios.Stage1MinRatio > c.StageRatios.Stage1MaxRatio {
+		addf("stage_ratios.stage1_min_ratio: 不能大于 stage1_max_ratio（%g > %g）", c.StageRatios.Stage1MinRatio, c.StageRatios.Stage1MaxRatio)
+	}
+	if c.StageRatios.UpperDeviation < 0 {
+		addf("stage_ratios.upper_deviation: 不能为负数，当前为 %g", c.StageRatios.UpperDeviation)
+	}
+
+	// 运行参数
+	if c.Settings.BatchSize < 0 {
+		addf("settings.batch_size: 不能为负数，当前为 %d", c.Settings.BatchSize)
+	}
+	if c.Settings.S3Import.MaxConcurrency < 0 {
+		addf("settings.s3_import.max_concurrency: 不能为负数，当前为 %d", c.Settings.S3Import.MaxConcurrency)
+	}
+	if c.Settings.S3Import.RetryAttempts < 0 {
+		addf("settings.s3_import.retry_attempts: 不能为负数，当前为 %d", c.Settings.S3Import.RetryAttempts)
+	}
+	if c.Settings.S3Import.RetryBackoffMs < 0 {
+		addf("settings.s3_import.retry_backoff_ms: 不能为负数，当前为 %d", c.Settings.S3Import.RetryBackoffMs)
+	}
+	if c.Settings.S3Upload.MaxConcurrency < 0 {
+		addf("settings.s3_upload.max_concurrency: 不能为负数，当前为 %d", c.Settings.S3Upload.MaxConcurrency)
+	}
+	if size := c.Settings.S3Upload.PartSizeMB; size != 0 && size < minS3PartSizeMB {
+		addf("settings.s3_upload.part_size_mb: 不能小于 %d（S3 分片下限），当前为 %d", minS3PartSizeMB, size)
+	}
+	if c.Settings.Generation.MaxConcurrency < 0 {
+		addf("settings.generation.max_concurrency: 不能为负数，当前为 %d", c.Settings.Generation.MaxConcurrency)
+	}
+	if c.Settings.Generation.PerGameConcurrency < 0 {
+		addf("settings.generation.per_game_concurrency: 不能为负数，当前为 %d", c.Settings.Generation.PerGameConcurrency)
+	}
+	if c.Settings.Database.MaxOpenConns < 0 {
+		addf("settings.database.max_open_conns: 不能为负数，当前为 %d", c.Settings.Database.MaxOpenConns)
+	}
+	if c.Settings.Database.MaxIdleConns < 0 {
+		addf("settings.database.max_idle_conns: 不能为负数，当前为 %d", c.Settings.Database.MaxIdleConns)
+	}
+
+	// S3
+	if c.S3.Enabled {
+		if c.S3.Bucket == "" {
+			addf("s3.bucket: s3.enabled 为 true 时不能为空")
+		}
+		if c.S3.Region == "" {
+			addf("s3.region: s3.enabled 为 true 时不能为空")
+		}
+	}
+	// static 的密钥可能来自运行时的环境变量或 .env，这里不强制要求写在配置文件中
+	switch c.S3.Credentials {
+	case "", s3CredentialsStatic, s3CredentialsDefault:
+	case s3CredentialsProfile:
+		if c.S3.Profile == "" {
+			addf("s3.profile: credentials 为 profile 时不能为空")
+		}
+	case s3CredentialsWebIdentity:
+		roleARN, tokenFile, _ := c.s3WebIdentitySettings()
+		if roleARN == "" {
+			addf("s3.role_arn: credentials 为 web_identity 时不能为空（或设置 AWS_ROLE_ARN）")
+		}
+		if tokenFile == "" {
+			addf("s3.web_identity_token_file: credentials 为 web_identity 时不能为空（或设置 AWS_WEB_IDENTITY_TOKEN_FILE）")
+		}
+	default:
+		addf("s3.credentials: 无效的凭据来源 %s（可选 static/profile/default/web_identity）", c.S3.Credentials)
+	}
+	normalPrefix, fbPrefix := c.S3PrefixTemplates()
+	if !strings.Contains(normalPrefix, s3GameIDPlaceholder) {
+		addf("s3.normal_prefix: 必须包含 %s，当前为 %q", s3GameIDPlaceholder, normalPrefix)
+	}
+	if !strings.Contains(fbPrefix, s3GameIDPlaceholder) {
+		addf("s3.fb_prefix: 必须包含 %s，当前为 %q", s3GameIDPlaceholder, fbPrefix)
+	}
+	// 前缀互相包含时列出 normal 会把 fb 文件一起列出
+	if n, f := expandS3Prefix(normalPrefix, 1), expandS3Prefix(fbPrefix, 1); strings.HasPrefix(n, f) || strings.HasPrefix(f, n) {
+		addf("s3.fb_prefix: 不能与 s3.normal_prefix 相同或互为前缀（%q, %q）", normalPrefix, fbPrefix)
+	}
+	if c.S3.Endpoint != "" {
+		if u, err := url.Parse(c.S3.Endpoint); err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			addf("s3.endpoint: 必须为 http(s)://host[:port] 形式的 URL，当前为 %q", c.S3.Endpoint)
+		}
+	}
+
+	return append(problems, c.rtpLevelProblems()...)
+}
+
+// pingEnvironment 连接指定环境并执行一次 Ping，用于 config check 的连通性检查
+func pingEnvironment(config *Config, env string) (*DatabaseConfig, time.Duration, error) {
+	dbConfig, err := config.GetDatabaseConfig(env)
+	if err != nil {
+		return nil, 0, err
+	}
+	db, err := sql.Open("postgres", dbConfig.DSN())
+	if err != nil {
+		return dbConfig, 0, err
+	}
+	defer db.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), configPingTimeout)
+	defer cancel()
+	start := time.Now()
+	if err := db.PingContext(ctx); err != nil {
+		return dbConfig, 0, err
+	}
+	return dbConfig, time.Since(start), nil
+}
+
+// configCheckEnvs 需要检查连通性的环境：命令行指定的环境，否则为默认环境、配置文件中声明的环境
+// 以及凭据可用的内置环境（未设置环境变量的内置环境跳过）
+func configCheckEnvs(config *Config, args []string) []string {
+	if len(args) > 0 {
+		envs := make([]string, 0, len(args))
+		for _, arg := range args {
+			envs = append(envs, ResolveEnv(arg))
+		}
+		return envs
+	}
+
+	defaultEnv := ResolveEnv(config.DefaultEnv)
+	envs := []string{defaultEnv}
+	for _, name := range config.environmentNames() {
+		if name == defaultEnv {
+			continue
+		}
+		if config.Environments[name].declared {
+			envs = append(envs, name)
+		} else if _, err := config.GetDatabaseConfig(name); err == nil {
+			envs = append(envs, name)
+		}
+	}
+	return envs
+}
+
+// runConfigCheckMode 校验配置：config check [--env e1,e2] [--no-connect]
+// 输出全部配置问题（带 YAML 路径）与各环境的连通性，存在任何问题时以非零状态退出
+func runConfigCheckMode(envs []string, noConnect bool) {
+	filename := configPath
+	fmt.Printf("🔍 校验配置文件: %s\n", filename)
+	config, err := readConfig(filename)
+	if err != nil {
+		fmt.Printf("❌ %v\n", err)
+		os.Exit(1)
+	}
+
+	problems := config.Problems()
+	if len(problems) == 0 {
+		fmt.Println("✅ 配置项校验通过")
+	} else {
+		fmt.Printf("❌ 发现 %d 个配置问题:\n", len(problems))
+		for _, p := range problems {
+			fmt.Printf("  - %s\n", p)
+		}
+	}
+
+	unreachable := 0
+	if !noConnect {
+		fmt.Println("\n🌐 环境连通性:")
+		for _, env := range configCheckEnvs(config, envs) {
+			label := env
+			if config.Environments[env].IsProtected() {
+				label += " 🛡️受保护"
+			}
+			dbConfig, elapsed, err := pingEnvironment(config, env)
+			if err != nil {
+				unreachable++
+				if dbConfig != nil {
+					fmt.Printf("  ❌ %s (%s:%d/%s): %v\n", label, dbConfig.Host, dbConfig.Port, dbConfig.Dbname, err)
+				} else {
+					fmt.Printf("  ❌ %s: %v\n", label, err)
+				}
+				continue
+			}
+			fmt.Printf("  ✅ %s (%s:%d/%s) %v\n", label, dbConfig.Host, dbConfig.Port, dbConfig.Dbname, elapsed.Round(time.Millisecond))
+		}
+	}
+
+	if len(problems) > 0 || unreachable > 0 {
+		fmt.Printf("\n❌ 配置检查未通过: %d 个配置问题, %d 个环境无法连接\n", len(problems), unreachable)
+		os.Exit(1)
+	}
+	fmt.Println("\n✅ 配置检查通过")
+}
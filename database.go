@@ -27,17 +27,7 @@ func NewDatabase(config *Config, env string) (*Database, error) {
 		return nil, err
 	}
 
-	dsn := fmt.Sprintf("host=%s port=%d user=%s password=%s dbname=%s sslmode=%s TimeZone=%s",
-		dbConfig.Host,
-		dbConfig.Port,
-		dbConfig.User,
-		dbConfig.Password,
-		dbConfig.Dbname,
-		dbConfig.SSLMode,
-		dbConfig.Timezone,
-	)
-
-	db, err := sql.Open("postgres", dsn)
+	db, err := sql.Open("postgres", dbConfig.DSN())
 	if err != nil {
 		return nil, fmt.Errorf("连接数据库失败: %v", err)
 	}
@@ -177,11 +167,26 @@ func (d *Database) BeginWithRetry() (*sql.Tx, error) {
 	return nil, fmt.Errorf("经过 %d 次重试后仍无法开始事务", maxRetries)
 }
 
+// ForConfig 绑定到指定游戏配置的数据库视图，与 d 共用连接池；源表名、查询超时等按该配置计算
+func (d *Database) ForConfig(config *Config) *Database {
+	return &Database{DB: d.DB, Config: config, lastPingTime: d.lastPingTime, pingInterval: d.pingInterval}
+}
+
 // GetTableName 获取源表名（用于读取数据）
 func (d *Database) GetTableName() string {
 	return fmt.Sprintf("\"%s%d\"", d.Config.Tables.SourceTablePrefix, d.Config.Game.ID)
 }
 
+// CountSourceRows 源表总行数，写入生成清单用于追溯生成时的源数据规模
+func (d *Database) CountSourceRows() (int64, error) {
+	var count int64
+	query := fmt.Sprintf(`SELECT count(1) FROM %s`, d.GetTableName())
+	if err := d.DB.QueryRow(query).Scan(&count); err != nil {
+		return 0, fmt.Errorf("统计源表 %s 行数失败: %v", d.GetTableName(), err)
+	}
+	return count, nil
+}
+
 // GetWinData 获取所有中奖数据 (aw > 0 且 aw/tb < 100)
 func (d *Database) GetWinData() ([]GameResultData, error) {
 	tableName := d.GetTableName()
@@ -388,71 +393,6 @@ func (d *Database) GetNoWinDataFb() ([]GameResultData, error) {
 	return data, nil
 }
 
-// GetWinDataForFilling 获取用于填充的中奖数据，按金额排序并限制数量
-// remainingWin: 需要填充的金额
-// excludeIds: 已经使用的数据ID列表
-// limit: 限制返回的数据条数
-func (d *Database) GetWinDataForFilling(remainingWin float64, excludeIds []int, limit int) ([]GameResultData, error) {
-	tableName := d.GetTableName()
-
-	// 构建排除ID的SQL条件
-	var excludeCondition string
-	var args []interface{}
-	argIndex := 1
-
-	if len(excludeIds) > 0 {
-		placeholders := make([]string, len(excludeIds))
-		for i := range excludeIds {
-			placeholders[i] = fmt.Sprintf("$%d", argIndex)
-			args = append(args, excludeIds[i])
-			argIndex++
-		}
-		excludeCondition = fmt.Sprintf("AND id NOT IN (%s)", strings.Join(placeholders, ","))
-	}
-
-	// 查询条件：
-	// 1. 中奖金额 > 0 且 < tb * 100
-	// 2. 中奖金额 <= remainingWin（比需要填充金额低的）
-	// 3. 排除已使用的ID
-	// 4. 按中奖金额降序排列，优先选择金额大的
-	// 5. 限制返回条数
-	query := fmt.Sprintf(`
-        SELECT id, tb, aw, gwt, sp, fb, gd, "createdAt", "updatedAt"
-        FROM %s 
-        WHERE aw > 0 
-				And fb != 2
-        AND aw < tb * 100
-        AND aw <= $%d
-        %s
-        ORDER BY (CASE WHEN gwt IN (2,3,4) THEN 1 ELSE 0 END), aw DESC
-        LIMIT $%d
-    `, tableName, argIndex, excludeCondition, argIndex+1)
-
-	args = append(args, remainingWin, limit)
-
-	rows, err := d.DB.Query(query, args...)
-	if err != nil {
-		return nil, fmt.Errorf("查询填充数据失败: %v", err)
-	}
-	defer rows.Close()
-
-	var data []GameResultData
-	for rows.Next() {
-		var item GameResultData
-		err := rows.Scan(
-			&item.ID, &item.TB, &item.AW, &item.GWT,
-			&item.SP, &item.FB, &item.GD,
-			&item.CreatedAt, &item.UpdatedAt,
-		)
-		if err != nil {
-			return nil, err
-		}
-		data = append(data, item)
-	}
-
-	return data, nil
-}
-
 // GetWinDataForFillingFb 获取用于填充的购买模式中奖数据
 // 条件：aw > 0 且 aw < tb*100 且 aw <= remainingWin，gwt <= 1，fb = 2，sp = true
 // 排除 excludeIds，按金额从大到小排序，限制返回条数
@@ -483,7 +423,7 @@ func (d *Database) GetWinDataForFillingFb(remainingWin float64, excludeIds []int
         AND fb = 2
         AND sp = true
         %s
-        ORDER BY aw DESC
+        ORDER BY aw DESC, id
         LIMIT $%d
     `, tableName, argIndex, excludeCondition, argIndex+1)
 
@@ -514,61 +454,43 @@ func (d *Database) GetWinDataForFillingFb(remainingWin float64, excludeIds []int
 	return data, nil
 }
 
-// GetBestSingleMatch 获取最接近目标金额的单条中奖数据
-// targetWin: 目标中奖金额
-// excludeIds: 已经使用的数据ID列表
-// maxDeviation: 最大允许偏差（如0.005表示允许5%的偏差）
-func (d *Database) GetBestSingleMatch(targetWin float64, excludeIds []int, maxDeviation float64) (*GameResultData, error) {
-	tableName := d.GetTableName()
-
-	// 构建排除ID的SQL条件
-	var excludeCondition string
-	var args []interface{}
-	argIndex := 1
-
-	if len(excludeIds) > 0 {
-		placeholders := make([]string, len(excludeIds))
-		for i := range excludeIds {
-			placeholders[i] = fmt.Sprintf("$%d", argIndex)
-			args = append(args, excludeIds[i])
-			argIndex++
-		}
-		excludeCondition = fmt.Sprintf("AND id NOT IN (%s)", strings.Join(placeholders, ","))
-	}
+// OutputSliceStats 目标表中一个 (rtpLevel, srNumber) 切片的聚合统计
+type OutputSliceStats struct {
+	RtpLevel   float64
+	SrNumber   int
+	Count      int
+	SumBet     Money
+	SumWin     Money
+	MinSrId    int
+	MaxSrId    int
+	DistinctId int
+}
 
-	// 查询条件：
-	// 1. 中奖金额 > 0 且 < tb * 100
-	// 2. 中奖金额在允许偏差范围内
-	// 3. 排除已使用的ID
-	// 4. 按与目标金额的差值排序，选择最接近的
+// AggregateOutputTable 按 rtpLevel、srNumber 聚合目标表：条数、总投注、总中奖、srId 范围与去重数
+func (d *Database) AggregateOutputTable(tableName string) ([]OutputSliceStats, error) {
 	query := fmt.Sprintf(`
-		SELECT id, tb, aw, gwt, sp, fb, gd, "createdAt", "updatedAt"
-		FROM %s 
-		WHERE aw > 0
-		And fb != 2
-		AND aw < tb * 100
-		AND aw >= $%d * (1 - $%d)
-		AND aw <= $%d * (1 + $%d)
-		%s
-		ORDER BY ABS(aw - $%d)
-		LIMIT 1
-	`, tableName, argIndex, argIndex+1, argIndex+2, argIndex+3, excludeCondition, argIndex+4)
-
-	args = append(args, targetWin, maxDeviation, targetWin, maxDeviation, targetWin)
-
-	var item GameResultData
-	err := d.DB.QueryRow(query, args...).Scan(
-		&item.ID, &item.TB, &item.AW, &item.GWT,
-		&item.SP, &item.FB, &item.GD,
-		&item.CreatedAt, &item.UpdatedAt,
-	)
+        SELECT round("rtpLevel"::numeric, 1), "srNumber", count(1), COALESCE(sum(bet), 0), COALESCE(sum(win), 0),
+               min("srId"), max("srId"), count(DISTINCT "srId")
+        FROM "%s"
+        GROUP BY "rtpLevel", "srNumber"
+        ORDER BY "rtpLevel", "srNumber"
+    `, tableName)
 
+	ctx, cancel := context.WithTimeout(context.Background(), time.Duration(d.Config.Settings.Timeout)*time.Second)
+	defer cancel()
+	rows, err := d.DB.QueryContext(ctx, query)
 	if err != nil {
-		if err == sql.ErrNoRows {
-			return nil, nil // 没有找到匹配的数据
-		}
-		return nil, fmt.Errorf("查询最佳匹配数据失败: %v", err)
+		return nil, fmt.Errorf("聚合目标表 %s 失败: %v", tableName, err)
 	}
+	defer rows.Close()
 
-	return &item, nil
+	var stats []OutputSliceStats
+	for rows.Next() {
+		var s OutputSliceStats
+		if err := rows.Scan(&s.RtpLevel, &s.SrNumber, &s.Count, &s.SumBet, &s.SumWin, &s.MinSrId, &s.MaxSrId, &s.DistinctId); err != nil {
+			return nil, err
+		}
+		stats = append(stats, s)
+	}
+	return stats, rows.Err()
 }
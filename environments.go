@@ -0,0 +1,294 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// 凭据来源
+const (
+	credentialsConfig = "config" // 直接使用配置文件中的 host/port/user/password/dbname（默认）
+	credentialsEnv    = "env"    // 从 <env_prefix>_DB_HOST 等环境变量读取，未设置的项回退到配置文件中的值
+)
+
+// validSSLModes lib/pq 支持的 sslmode
+var validSSLModes = map[string]bool{
+	"disable": true, "allow": true, "prefer": true, "require": true, "verify-ca": true, "verify-full": true,
+}
+
+// builtinEnvironments 内置环境：config.yaml 中未声明的同名环境直接使用，已声明的环境中留空的字段也以此补齐
+var builtinEnvironments = map[string]DatabaseConfig{
+	"local":   {Aliases: []string{"l"}},
+	"hk-test": remoteEnvironment("ht", "HT"),
+	"br-test": remoteEnvironment("bt", "BT"),
+	"br-prod": protectedEnvironment(remoteEnvironment("bp", "BP")),
+	"us-prod": protectedEnvironment(remoteEnvironment("up", "UP")),
+	"hk-prod": protectedEnvironment(remoteEnvironment("hp", "HP")),
+}
+
+// remoteEnvironment 从环境变量读取凭据的远程环境
+func remoteEnvironment(alias, prefix string) DatabaseConfig {
+	return DatabaseConfig{
+		Aliases:     []string{alias},
+		Credentials: credentialsEnv,
+		EnvPrefix:   prefix,
+		SSLMode:     "require",
+		Timezone:    "UTC",
+	}
+}
+
+// protectedEnvironment 标记为受保护环境（内置的正式环境默认受保护）
+func protectedEnvironment(d DatabaseConfig) DatabaseConfig {
+	protected := true
+	d.Protected = &protected
+	return d
+}
+
+// IsProtected 是否为受保护环境
+func (d DatabaseConfig) IsProtected() bool {
+	return d.Protected != nil && *d.Protected
+}
+
+// envAliases 环境名称及别名 → 完整环境名
+// 命令行解析早于加载配置，因此保存在包级变量中，读取配置后追加配置中声明的环境
+var envAliases = make(map[string]string)
+
+func init() {
+	registerEnvironments(builtinEnvironments)
+}
+
+// registerEnvironments 登记环境名称与别名
+func registerEnvironments(envs map[string]DatabaseConfig) {
+	for name, env := range envs {
+		envAliases[name] = name
+		for _, alias := range env.Aliases {
+			envAliases[alias] = name
+		}
+	}
+}
+
+// preloadEnvironments 解析命令行前读取配置中声明的环境与别名
+// 读取失败时只保留内置环境，具体错误由各命令加载配置时报告
+func preloadEnvironments(filename string) {
+	readConfig(filename)
+}
+
+// ResolveEnv 解析环境参数
+func ResolveEnv(envArg string) string {
+	if fullEnv, exists := envAliases[envArg]; exists {
+		return fullEnv
+	}
+	return envArg // 如果不在映射表中，直接返回原值
+}
+
+// IsEnv 检查参数是否为环境代码
+func IsEnv(arg string) bool {
+	_, exists := envAliases[arg]
+	return exists
+}
+
+// envCodesHelp 帮助信息中的环境代码列表，如 "hk-test/ht, local/l"
+func envCodesHelp() string {
+	aliases := make(map[string][]string)
+	for code, name := range envAliases {
+		if code != name {
+			aliases[name] = append(aliases[name], code)
+		}
+	}
+	names := make([]string, 0, len(aliases))
+	for code, name := range envAliases {
+		if code == name {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+	codes := make([]string, 0, len(names))
+	for _, name := range names {
+		sort.Strings(aliases[name])
+		codes = append(codes, strings.Join(append([]string{name}, aliases[name]...), "/"))
+	}
+	return strings.Join(codes, ", ")
+}
+
+// withDefaults 用内置环境补齐声明中留空的字段
+func (d DatabaseConfig) withDefaults(builtin DatabaseConfig) DatabaseConfig {
+	if len(d.Aliases) == 0 {
+		d.Aliases = builtin.Aliases
+	}
+	if d.Credentials == "" {
+		d.Credentials = builtin.Credentials
+	}
+	if d.EnvPrefix == "" {
+		d.EnvPrefix = builtin.EnvPrefix
+	}
+	if d.SSLMode == "" {
+		d.SSLMode = builtin.SSLMode
+	}
+	if d.Timezone == "" {
+		d.Timezone = builtin.Timezone
+	}
+	if d.Protected == nil {
+		d.Protected = builtin.Protected
+	}
+	return d
+}
+
+// applyEnvironmentDefaults 合并配置中声明的环境与内置环境，并登记全部名称与别名
+func (c *Config) applyEnvironmentDefaults() {
+	declared := c.Environments
+	// 向后兼容：未配置 environments 时使用传统的 database.postgres 作为 local
+	if len(declared) == 0 && c.Database.Postgres.Host != "" {
+		declared = map[string]DatabaseConfig{"local": c.Database.Postgres}
+	}
+
+	c.Environments = make(map[string]DatabaseConfig, len(declared)+len(builtinEnvironments))
+	for name, env := range declared {
+		if builtin, ok := builtinEnvironments[name]; ok {
+			env = env.withDefaults(builtin)
+		}
+		env.declared = true
+		c.Environments[name] = env
+	}
+	for name, builtin := range builtinEnvironments {
+		if _, ok := c.Environments[name]; !ok {
+			c.Environments[name] = builtin
+		}
+	}
+	registerEnvironments(c.Environments)
+}
+
+// GetDatabaseConfig 根据环境获取数据库配置
+func (c *Config) GetDatabaseConfig(env string) (*DatabaseConfig, error) {
+	if env == "" {
+		env = c.DefaultEnv
+	}
+
+	// 解析环境参数（支持别名）
+	name := ResolveEnv(env)
+	envConfig, ok := c.Environments[name]
+	if !ok {
+		return nil, fmt.Errorf("不支持的环境: %s", env)
+	}
+
+	switch envConfig.Credentials {
+	case "", credentialsConfig:
+		return &envConfig, nil
+	case credentialsEnv:
+		return databaseConfigFromEnv(envConfig)
+	}
+	return nil, fmt.Errorf("环境 %s 的凭据来源无效: %s", name, envConfig.Credentials)
+}
+
+// databaseConfigFromEnv 从 <env_prefix>_DB_* 环境变量读取数据库配置，未设置的项回退到配置文件中的值
+func databaseConfigFromEnv(envConfig DatabaseConfig) (*DatabaseConfig, error) {
+	prefix := envConfig.EnvPrefix
+	lookup := func(key, fallback string) (string, error) {
+		if v := os.Getenv(prefix + "_DB_" + key); v != "" {
+			return v, nil
+		}
+		if fallback != "" {
+			return fallback, nil
+		}
+		return "", fmt.Errorf("环境变量 %s_DB_%s 未设置", prefix, key)
+	}
+
+	portFallback := ""
+	if envConfig.Port > 0 {
+		portFallback = strconv.Itoa(envConfig.Port)
+	}
+
+	dbConfig := envConfig
+	var err error
+	if dbConfig.Host, err = lookup("HOST", envConfig.Host); err != nil {
+		return nil, err
+	}
+	portStr, err := lookup("PORT", portFallback)
+	if err != nil {
+		return nil, err
+	}
+	if dbConfig.User, err = lookup("USER", envConfig.User); err != nil {
+		return nil, err
+	}
+	if dbConfig.Password, err = lookup("PASSWORD", envConfig.Password); err != nil {
+		return nil, err
+	}
+	if dbConfig.Dbname, err = lookup("NAME", envConfig.Dbname); err != nil {
+		return nil, err
+	}
+
+	// 解析端口号
+	if dbConfig.Port, err = strconv.Atoi(portStr); err != nil {
+		return nil, fmt.Errorf("环境变量 %s_DB_PORT 不是有效的整数: %s", prefix, portStr)
+	}
+	return &dbConfig, nil
+}
+
+// environmentNames 按名称排序的全部环境
+func (c *Config) environmentNames() []string {
+	names := make([]string, 0, len(c.Environments))
+	for name := range c.Environments {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// environmentProblems 校验 default_env 与 environments 中声明的环境、别名冲突
+func (c *Config) environmentProblems() []string {
+	var problems []string
+	addf := func(format string, a ...interface{}) {
+		problems = append(problems, fmt.Sprintf(format, a...))
+	}
+
+	if _, ok := c.Environments[ResolveEnv(c.DefaultEnv)]; !ok {
+		addf("default_env: 未知环境 %s（既不在 environments 中，也不是内置环境或别名）", c.DefaultEnv)
+	}
+
+	names := c.environmentNames()
+	owner := make(map[string]string, len(names))
+	for _, name := range names {
+		owner[name] = name
+	}
+	for _, name := range names {
+		env := c.Environments[name]
+		for i, alias := range env.Aliases {
+			if other, taken := owner[alias]; taken && other != name {
+				addf("environments.%s.aliases[%d]: 别名 %s 已被环境 %s 使用", name, i, alias, other)
+				continue
+			}
+			owner[alias] = name
+		}
+
+		if !env.declared {
+			continue
+		}
+		switch env.Credentials {
+		case "", credentialsConfig:
+			if env.Host == "" {
+				addf("environments.%s.host: 不能为空", name)
+			}
+			if env.Dbname == "" {
+				addf("environments.%s.dbname: 不能为空", name)
+			}
+			if env.Port <= 0 || env.Port > 65535 {
+				addf("environments.%s.port: 必须在 [1, 65535] 内，当前为 %d", name, env.Port)
+			}
+		case credentialsEnv:
+			if env.EnvPrefix == "" {
+				addf("environments.%s.env_prefix: credentials 为 env 时不能为空", name)
+			}
+			if env.Port < 0 || env.Port > 65535 {
+				addf("environments.%s.port: 必须在 [1, 65535] 内，当前为 %d", name, env.Port)
+			}
+		default:
+			addf("environments.%s.credentials: 无效的凭据来源 %s（可选 config/env）", name, env.Credentials)
+		}
+		if env.SSLMode != "" && !validSSLModes[env.SSLMode] {
+			addf("environments.%s.sslmode: 无效的 sslmode %s", name, env.SSLMode)
+		}
+	}
+	return problems
+}
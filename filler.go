@@ -0,0 +1,429 @@
+package main
+
+import (
+	"fmt"
+	"math"
+	"math/rand"
+	"sort"
+)
+
+// fillInf 精确补齐 DP 中的不可达标记
+const fillInf = math.MaxUint16
+
+const (
+	// fillMaxCells 单次精确补齐允许的最大状态数（金额跨度 × 配额层数），超出时拒绝搜索而不是耗尽内存
+	fillMaxCells = 32 << 20
+	// fillMaxWork 单次精确补齐允许的最大计算量（状态数 × 捆绑项数）
+	fillMaxWork = 2_000_000_000
+)
+
+// FillRequest 精确补齐请求，金额单位为分（与 Money 相同）
+type FillRequest struct {
+	MinCents int64       // 需补充金额下限
+	MaxCents int64       // 需补充金额上限
+	MaxItems int         // 剩余可用名额
+	Quotas   PrizeQuotas // 剩余大奖/巨奖/超级巨奖配额
+}
+
+// FillInfeasibleError 精确补齐无解：在给定候选与约束下不存在落入区间的组合
+type FillInfeasibleError struct {
+	Request    FillRequest
+	Candidates int
+	Reason     string
+}
+
+func (e *FillInfeasibleError) Error() string {
+	return fmt.Sprintf("精确补齐无解: %s (区间 [%.2f, %.2f], 候选 %d 条, 剩余名额 %d, 剩余配额 大奖%d/巨奖%d/超级巨奖%d)",
+		e.Reason, Money(e.Request.MinCents), Money(e.Request.MaxCents), e.Candidates,
+		e.Request.MaxItems, e.Request.Quotas.Big, e.Request.Quotas.Mega, e.Request.Quotas.SuperMega)
+}
+
+// FillSearchLimitError 精确补齐搜索空间或计算量超过上限，未做搜索，无法判定是否有解
+type FillSearchLimitError struct {
+	Request FillRequest
+	Reason  string
+}
+
+func (e *FillSearchLimitError) Error() string {
+	return fmt.Sprintf("精确补齐未能判定是否有解: %s (区间 [%.2f, %.2f], 剩余名额 %d)",
+		e.Reason, Money(e.Request.MinCents), Money(e.Request.MaxCents), e.Request.MaxItems)
+}
+
+// prizeClass 奖项类别：0=普通 1=大奖 2=巨奖 3=超级巨奖
+func prizeClass(gwt int) int {
+	switch gwt {
+	case 2:
+		return 1
+	case 3:
+		return 2
+	case 4:
+		return 3
+	}
+	return 0
+}
+
+// fillBundle 同金额同类别候选按二进制拆分后的捆绑项（有界背包）
+type fillBundle struct {
+	group int
+	count int
+	cents int64
+	class int
+}
+
+// fillLayers 配额层：q[c] 为第 c 类最多可用条数，层数为 (q1+1)*(q2+1)*(q3+1)
+type fillLayers [4]int
+
+func (q fillLayers) size() int {
+	return (q[1] + 1) * (q[2] + 1) * (q[3] + 1)
+}
+
+func (q fillLayers) index(k fillLayers) int {
+	return (k[1]*(q[2]+1)+k[2])*(q[3]+1) + k[3]
+}
+
+func (q fillLayers) stride(class int) int {
+	switch class {
+	case 1:
+		return (q[2] + 1) * (q[3] + 1)
+	case 2:
+		return q[3] + 1
+	case 3:
+		return 1
+	}
+	return 0
+}
+
+// each 遍历所有配额层组合
+func (q fillLayers) each(fn func(k fillLayers)) {
+	for k1 := 0; k1 <= q[1]; k1++ {
+		for k2 := 0; k2 <= q[2]; k2++ {
+			for k3 := 0; k3 <= q[3]; k3++ {
+				fn(fillLayers{0, k1, k2, k3})
+			}
+		}
+	}
+}
+
+// fillTable 最少条数 DP：dp[layer*span+s] 为恰好凑出 s 分、各配额类恰好使用 layer 对应条数时的最少总条数
+func fillTable(bundles []fillBundle, span int64, q fillLayers) []uint16 {
+	layers := q.size()
+	dp := make([]uint16, int64(layers)*span)
+	for i := range dp {
+		dp[i] = fillInf
+	}
+	dp[0] = 0
+
+	for _, b := range bundles {
+		if b.cents >= span {
+			continue
+		}
+		cnt := uint16(b.count)
+		if b.class == 0 {
+			for l := 0; l < layers; l++ {
+				row := dp[int64(l)*span : int64(l+1)*span]
+				for s := span - 1; s >= b.cents; s-- {
+					if prev := row[s-b.cents]; prev != fillInf && prev+cnt < row[s] {
+						row[s] = prev + cnt
+					}
+				}
+			}
+			continue
+		}
+		// 配额类：目标层比来源层多 count 条该类，按层号从大到小处理保证每个捆绑项最多使用一次
+		shift := q.stride(b.class) * b.count
+		for l := layers - 1; l >= 0; l-- {
+			var k fillLayers
+			rest := l
+			k[3] = rest % (q[3] + 1)
+			rest /= q[3] + 1
+			k[2] = rest % (q[2] + 1)
+			k[1] = rest / (q[2] + 1)
+			if k[b.class] < b.count {
+				continue
+			}
+			dst := dp[int64(l)*span : int64(l+1)*span]
+			src := dp[int64(l-shift)*span : int64(l-shift+1)*span]
+			for s := span - 1; s >= b.cents; s-- {
+				if prev := src[s-b.cents]; prev != fillInf && prev+cnt < dst[s] {
+					dst[s] = prev + cnt
+				}
+			}
+		}
+	}
+	return dp
+}
+
+// fillReconstruct 分治回溯：把捆绑项分成两半分别做 DP，找到和为目标的切分后递归，内存只需 O(状态数)
+func fillReconstruct(bundles []fillBundle, target int64, k fillLayers, count int, take []bool) error {
+	if count == 0 {
+		return nil
+	}
+	if len(bundles) == 1 {
+		take[0] = true
+		return nil
+	}
+	mid := len(bundles) / 2
+	span := target + 1
+	left := fillTable(bundles[:mid], span, k)
+	right := fillTable(bundles[mid:], span, k)
+
+	found := false
+	var err error
+	k.each(func(kl fillLayers) {
+		if found {
+			return
+		}
+		kr := fillLayers{0, k[1] - kl[1], k[2] - kl[2], k[3] - kl[3]}
+		lrow := left[int64(k.index(kl))*span:]
+		rrow := right[int64(k.index(kr))*span:]
+		for tl := int64(0); tl <= target; tl++ {
+			a, b := lrow[tl], rrow[target-tl]
+			if a == fillInf || b == fillInf || int(a)+int(b) != count {
+				continue
+			}
+			left, right = nil, nil
+			if err = fillReconstruct(bundles[:mid], tl, kl, int(a), take[:mid]); err == nil {
+				err = fillReconstruct(bundles[mid:], target-tl, kr, int(b), take[mid:])
+			}
+			found = true
+			return
+		}
+	})
+	if !found {
+		return fmt.Errorf("精确补齐回溯失败：DP 状态不一致（目标 %d 分, %d 条）", target, count)
+	}
+	return err
+}
+
+// solveWinFill 在候选中寻找总金额（分）落入 [MinCents, MaxCents] 的组合
+// 约束：条数不超过 MaxItems，大奖/巨奖/超级巨奖条数不超过剩余配额；同一候选最多使用一次
+// 有解时优先选择最接近下限的金额，同金额下选择条数最少的组合；返回候选下标
+func solveWinFill(candidates []GameResultData, req FillRequest, rng *rand.Rand) ([]int, error) {
+	if req.MinCents < 0 {
+		req.MinCents = 0
+	}
+	if req.MaxCents < req.MinCents {
+		return nil, &FillInfeasibleError{Request: req, Candidates: len(candidates), Reason: "目标区间为空"}
+	}
+	if req.MinCents == 0 {
+		return nil, nil
+	}
+	if req.MaxItems <= 0 {
+		return nil, &FillInfeasibleError{Request: req, Candidates: len(candidates), Reason: "没有剩余名额"}
+	}
+
+	quotaOf := [4]int{0, req.Quotas.Big, req.Quotas.Mega, req.Quotas.SuperMega}
+
+	// 按（金额, 类别）分组，组内按ID排序保证确定性
+	type groupKey struct {
+		cents int64
+		class int
+	}
+	groupIndex := make(map[groupKey]int)
+	var groups [][]int
+	var keys []groupKey
+	order := make([]int, len(candidates))
+	for i := range order {
+		order[i] = i
+	}
+	sort.SliceStable(order, func(a, b int) bool { return candidates[order[a]].ID < candidates[order[b]].ID })
+	for _, i := range order {
+		item := candidates[i]
+		cents := int64(item.AW)
+		class := prizeClass(item.GWT)
+		if cents <= 0 || cents > req.MaxCents || quotaOf[class] == 0 && class != 0 {
+			continue
+		}
+		key := groupKey{cents, class}
+		g, ok := groupIndex[key]
+		if !ok {
+			g = len(groups)
+			groupIndex[key] = g
+			groups = append(groups, nil)
+			keys = append(keys, key)
+		}
+		groups[g] = append(groups[g], i)
+	}
+
+	// 有效配额层：不超过剩余配额、该类候选条数以及区间上限能容纳的条数
+	var q fillLayers
+	var classCount, classMin [4]int64
+	for g, key := range keys {
+		classCount[key.class] += int64(len(groups[g]))
+		if classMin[key.class] == 0 || key.cents < classMin[key.class] {
+			classMin[key.class] = key.cents
+		}
+	}
+	for c := 1; c <= 3; c++ {
+		if classCount[c] == 0 {
+			continue
+		}
+		limit := int64(quotaOf[c])
+		if classCount[c] < limit {
+			limit = classCount[c]
+		}
+		if fit := req.MaxCents / classMin[c]; fit < limit {
+			limit = fit
+		}
+		if int64(req.MaxItems) < limit {
+			limit = int64(req.MaxItems)
+		}
+		q[c] = int(limit)
+	}
+
+	span := req.MaxCents + 1
+	cells := span * int64(q.size())
+	if cells > fillMaxCells {
+		return nil, &FillSearchLimitError{Request: req,
+			Reason: fmt.Sprintf("搜索空间过大: 金额跨度 %.2f × 配额层 %d 超过上限 %d", Money(req.MaxCents), q.size(), fillMaxCells)}
+	}
+
+	// 有界背包：每组可用条数按二进制拆分为捆绑项
+	maxItems := req.MaxItems
+	if maxItems > fillInf-1 {
+		maxItems = fillInf - 1
+	}
+	if classCount[0] == 0 && q.size() == 1 {
+		return nil, &FillInfeasibleError{Request: req, Candidates: len(candidates), Reason: "没有金额不超过区间上限的可用候选"}
+	}
+	var bundles []fillBundle
+	for g, key := range keys {
+		avail := int64(len(groups[g]))
+		if fit := req.MaxCents / key.cents; fit < avail {
+			avail = fit
+		}
+		if key.class != 0 && int64(q[key.class]) < avail {
+			avail = int64(q[key.class])
+		}
+		if avail > fillInf-1 {
+			avail = fillInf - 1
+		}
+		for size := int64(1); avail > 0; size *= 2 {
+			n := size
+			if n > avail {
+				n = avail
+			}
+			bundles = append(bundles, fillBundle{group: g, count: int(n), cents: n * key.cents, class: key.class})
+			avail -= n
+		}
+	}
+
+	if work := cells * int64(len(bundles)); work > fillMaxWork {
+		return nil, &FillSearchLimitError{Request: req,
+			Reason: fmt.Sprintf("计算量过大: 状态 %d × 候选分组 %d 超过上限 %d", cells, len(bundles), int64(fillMaxWork))}
+	}
+
+	dp := fillTable(bundles, span, q)
+
+	// 选择最接近下限的金额，同金额取最少条数
+	bestSum, bestCount := int64(-1), fillInf
+	var bestLayer fillLayers
+	minReachCount := fillInf
+	for s := req.MinCents; s <= req.MaxCents && bestSum < 0; s++ {
+		q.each(func(k fillLayers) {
+			v := int(dp[int64(q.index(k))*span+s])
+			if v < minReachCount {
+				minReachCount = v
+			}
+			if v <= maxItems && v < bestCount {
+				bestSum, bestCount, bestLayer = s, v, k
+			}
+		})
+	}
+	if bestSum < 0 {
+		reason := "不存在金额落入区间的组合"
+		if minReachCount != fillInf {
+			reason = fmt.Sprintf("名额不足：最少需要 %d 条", minReachCount)
+		}
+		return nil, &FillInfeasibleError{Request: req, Candidates: len(candidates), Reason: reason}
+	}
+
+	take := make([]bool, len(bundles))
+	if err := fillReconstruct(bundles, bestSum, bestLayer, bestCount, take); err != nil {
+		return nil, err
+	}
+
+	// 组内随机选取具体候选
+	need := make([]int, len(groups))
+	for i, b := range bundles {
+		if take[i] {
+			need[b.group] += b.count
+		}
+	}
+	var picked []int
+	for g, n := range need {
+		if n == 0 {
+			continue
+		}
+		members := append([]int(nil), groups[g]...)
+		rng.Shuffle(len(members), func(i, j int) { members[i], members[j] = members[j], members[i] })
+		picked = append(picked, members[:n]...)
+	}
+	return picked, nil
+}
+
+// topUpWinExact 精确补齐：在未使用的中奖候选中寻找组合，使总中奖金额落入 [allowWin, maxWin]
+// selected 为按加入顺序排列的已选中奖数据；剩余候选无解时从后往前释放已选数据（每次翻倍）后重试，
+// 全部释放仍无解即说明整个候选池在约束下不存在可行组合，返回 *FillInfeasibleError。
+// 每次释放都会放宽补充金额上限，DP 状态数随之增长；超过搜索上限时返回 *FillSearchLimitError，
+// 表示未能判定是否有解（常见于总投注较大、已释放较多数据时），不能据此认为无解
+func topUpWinExact(selected []GameResultData, winPool []GameResultData, allowWin, maxWin Money, count int, quotas PrizeQuotas, rng *rand.Rand, printf func(format string, a ...interface{})) ([]GameResultData, error) {
+	allowCents, maxCents := int64(allowWin), int64(maxWin)
+
+	release := 0
+	for {
+		if release > len(selected) {
+			release = len(selected)
+		}
+		kept := selected[:len(selected)-release]
+
+		var keptCents int64
+		used := make(map[int]struct{}, len(kept))
+		left := quotas
+		for _, item := range kept {
+			keptCents += int64(item.AW)
+			used[item.ID] = struct{}{}
+			switch item.GWT {
+			case 2:
+				left.Big--
+			case 3:
+				left.Mega--
+			case 4:
+				left.SuperMega--
+			}
+		}
+		var candidates []GameResultData
+		for _, item := range winPool {
+			if _, ok := used[item.ID]; !ok {
+				candidates = append(candidates, item)
+			}
+		}
+
+		req := FillRequest{
+			MinCents: allowCents - keptCents,
+			MaxCents: maxCents - keptCents,
+			MaxItems: count - len(kept),
+			Quotas:   left,
+		}
+		picked, err := solveWinFill(candidates, req, rng)
+		if err == nil {
+			result := make([]GameResultData, 0, len(kept)+len(picked))
+			result = append(result, kept...)
+			for _, i := range picked {
+				result = append(result, candidates[i])
+			}
+			printf("✅ 精确补齐成功: 释放已选 %d 条, 补充 %d 条, 补充金额区间 [%.2f, %.2f]\n",
+				release, len(picked), Money(req.MinCents), Money(req.MaxCents))
+			return result, nil
+		}
+		if _, infeasible := err.(*FillInfeasibleError); !infeasible || release == len(selected) {
+			return nil, err
+		}
+		printf("🔍 %v，释放已选数据后重试\n", err)
+		if release == 0 {
+			release = 1
+		} else {
+			release *= 2
+		}
+	}
+}
@@ -0,0 +1,207 @@
+package main
+
+import (
+	"math/rand"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// fillItems 按金额（分）与 gwt 构造候选，ID 从 1 开始递增
+func fillItems(specs ...[2]int64) []GameResultData {
+	items := make([]GameResultData, len(specs))
+	for i, s := range specs {
+		items[i] = GameResultData{ID: i + 1, AW: Money(s[0]), GWT: int(s[1])}
+	}
+	return items
+}
+
+// repeatItems n 条同金额的普通中奖候选
+func repeatItems(cents int64, n int) [][2]int64 {
+	specs := make([][2]int64, n)
+	for i := range specs {
+		specs[i] = [2]int64{cents, 1}
+	}
+	return specs
+}
+
+func TestSolveWinFill(t *testing.T) {
+	tests := []struct {
+		name       string
+		candidates []GameResultData
+		req        FillRequest
+		wantCents  int64 // 有解时期望的补充金额
+		wantItems  int   // 有解时期望的条数，0 表示不检查
+		infeasible string
+	}{
+		{
+			name:       "精确命中",
+			candidates: fillItems([2]int64{100, 1}, [2]int64{250, 1}, [2]int64{400, 1}),
+			req:        FillRequest{MinCents: 650, MaxCents: 650, MaxItems: 10},
+			wantCents:  650,
+			wantItems:  2,
+		},
+		{
+			name:       "区间下限命中",
+			candidates: fillItems([2]int64{300, 1}, [2]int64{500, 1}, [2]int64{520, 1}),
+			req:        FillRequest{MinCents: 800, MaxCents: 900, MaxItems: 10},
+			wantCents:  800,
+		},
+		{
+			name:       "区间上限命中",
+			candidates: fillItems([2]int64{300, 1}, [2]int64{550, 1}, [2]int64{600, 1}),
+			req:        FillRequest{MinCents: 800, MaxCents: 850, MaxItems: 10},
+			wantCents:  850,
+		},
+		{
+			name:       "配额用尽时不能使用大奖",
+			candidates: fillItems([2]int64{500, 2}, [2]int64{200, 1}, [2]int64{200, 1}),
+			req:        FillRequest{MinCents: 500, MaxCents: 500, MaxItems: 10},
+			infeasible: "不存在金额落入区间的组合",
+		},
+		{
+			name:       "剩余配额内使用大奖",
+			candidates: fillItems([2]int64{500, 2}, [2]int64{200, 1}, [2]int64{200, 1}),
+			req:        FillRequest{MinCents: 500, MaxCents: 500, MaxItems: 10, Quotas: PrizeQuotas{Big: 1}},
+			wantCents:  500,
+			wantItems:  1,
+		},
+		{
+			name:       "名额不足",
+			candidates: fillItems(repeatItems(100, 10)...),
+			req:        FillRequest{MinCents: 500, MaxCents: 500, MaxItems: 4},
+			infeasible: "名额不足：最少需要 5 条",
+		},
+		{
+			name:       "名额恰好够用",
+			candidates: fillItems(repeatItems(100, 10)...),
+			req:        FillRequest{MinCents: 500, MaxCents: 500, MaxItems: 5},
+			wantCents:  500,
+			wantItems:  5,
+		},
+		{
+			name:       "无解",
+			candidates: fillItems([2]int64{300, 1}, [2]int64{700, 1}),
+			req:        FillRequest{MinCents: 500, MaxCents: 600, MaxItems: 10},
+			infeasible: "不存在金额落入区间的组合",
+		},
+		{
+			name:       "目标区间为空",
+			candidates: fillItems([2]int64{300, 1}),
+			req:        FillRequest{MinCents: 500, MaxCents: 400, MaxItems: 10},
+			infeasible: "目标区间为空",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			picked, err := solveWinFill(tt.candidates, tt.req, rand.New(rand.NewSource(1)))
+			if tt.infeasible != "" {
+				fe, ok := err.(*FillInfeasibleError)
+				if !ok {
+					t.Fatalf("期望 *FillInfeasibleError，得到 %v (picked=%v)", err, picked)
+				}
+				if fe.Reason != tt.infeasible {
+					t.Fatalf("无解原因 = %q，期望 %q", fe.Reason, tt.infeasible)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("意外错误: %v", err)
+			}
+
+			var sum int64
+			seen := make(map[int]bool)
+			big := 0
+			for _, i := range picked {
+				if seen[i] {
+					t.Fatalf("候选 %d 被重复使用", i)
+				}
+				seen[i] = true
+				sum += int64(tt.candidates[i].AW)
+				if tt.candidates[i].GWT == 2 {
+					big++
+				}
+			}
+			if sum != tt.wantCents {
+				t.Fatalf("补充金额 = %d，期望 %d", sum, tt.wantCents)
+			}
+			if tt.wantItems > 0 && len(picked) != tt.wantItems {
+				t.Fatalf("条数 = %d，期望 %d", len(picked), tt.wantItems)
+			}
+			if len(picked) > tt.req.MaxItems {
+				t.Fatalf("条数 %d 超过名额 %d", len(picked), tt.req.MaxItems)
+			}
+			if big > tt.req.Quotas.Big {
+				t.Fatalf("大奖 %d 条超过配额 %d", big, tt.req.Quotas.Big)
+			}
+		})
+	}
+}
+
+func TestSolveWinFillSearchLimit(t *testing.T) {
+	candidates := fillItems([2]int64{100, 1})
+	req := FillRequest{MinCents: 100, MaxCents: fillMaxCells + 1, MaxItems: 10}
+	_, err := solveWinFill(candidates, req, rand.New(rand.NewSource(1)))
+	if _, ok := err.(*FillSearchLimitError); !ok {
+		t.Fatalf("期望 *FillSearchLimitError，得到 %v", err)
+	}
+}
+
+func TestSolveWinFillDeterministic(t *testing.T) {
+	// 同金额候选很多时具体选中哪几条由 rng 决定，固定种子必须得到相同结果
+	specs := append(repeatItems(100, 50), repeatItems(300, 20)...)
+	candidates := fillItems(specs...)
+	req := FillRequest{MinCents: 2500, MaxCents: 2600, MaxItems: 30}
+
+	first, err := solveWinFill(candidates, req, rand.New(rand.NewSource(42)))
+	if err != nil {
+		t.Fatalf("意外错误: %v", err)
+	}
+	for i := 0; i < 5; i++ {
+		again, err := solveWinFill(candidates, req, rand.New(rand.NewSource(42)))
+		if err != nil {
+			t.Fatalf("意外错误: %v", err)
+		}
+		if !reflect.DeepEqual(first, again) {
+			t.Fatalf("固定种子结果不一致: %v vs %v", first, again)
+		}
+	}
+}
+
+func TestTopUpWinExact(t *testing.T) {
+	quiet := func(string, ...interface{}) {}
+	pool := fillItems([2]int64{400, 1}, [2]int64{300, 1}, [2]int64{300, 1})
+
+	t.Run("释放已选数据后命中", func(t *testing.T) {
+		// 已选 400 后还差 200，剩余候选凑不出；释放 400 后 300+300 命中
+		got, err := topUpWinExact(pool[:1], pool, 600, 600, 10, PrizeQuotas{}, rand.New(rand.NewSource(1)), quiet)
+		if err != nil {
+			t.Fatalf("意外错误: %v", err)
+		}
+		var sum Money
+		for _, item := range got {
+			sum += item.AW
+		}
+		if sum != 600 || len(got) != 2 {
+			t.Fatalf("结果 %v（合计 %d 分），期望 2 条合计 600 分", got, int64(sum))
+		}
+	})
+
+	t.Run("全部释放仍无解", func(t *testing.T) {
+		_, err := topUpWinExact(pool[:1], pool, 500, 550, 10, PrizeQuotas{}, rand.New(rand.NewSource(1)), quiet)
+		if _, ok := err.(*FillInfeasibleError); !ok {
+			t.Fatalf("期望 *FillInfeasibleError，得到 %v", err)
+		}
+	})
+
+	t.Run("搜索超出上限不视为无解", func(t *testing.T) {
+		_, err := topUpWinExact(nil, pool, 100, fillMaxCells+1, 10, PrizeQuotas{}, rand.New(rand.NewSource(1)), quiet)
+		if _, ok := err.(*FillSearchLimitError); !ok {
+			t.Fatalf("期望 *FillSearchLimitError，得到 %v", err)
+		}
+		if !strings.Contains(err.Error(), "未能判定") {
+			t.Fatalf("错误信息应说明未能判定: %v", err)
+		}
+	})
+}
@@ -0,0 +1,136 @@
+package main
+
+import (
+	"crypto/sha256"
+	"database/sql"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"os"
+)
+
+// importManifestTable 导入清单表：记录每个源文件导入到哪个目标表、校验和与状态，用于断点续导
+const importManifestTable = "import_manifest"
+
+// 导入清单状态
+const (
+	manifestStatusRunning   = "running"
+	manifestStatusCompleted = "completed"
+	manifestStatusSkipped   = "skipped"
+	manifestStatusFailed    = "failed"
+)
+
+// importSource 待导入文件在导入清单中的标识
+type importSource struct {
+	Source      string // 本地路径或 s3://bucket/key
+	Checksum    string // sha256:<hex>（本地文件）或 etag:<etag>（S3对象）
+	TargetTable string
+}
+
+// ensureImportManifest 创建导入清单表（如果不存在）
+func ensureImportManifest(db *Database) error {
+	query := fmt.Sprintf(`
+		CREATE TABLE IF NOT EXISTS "%s" (
+			"id" SERIAL PRIMARY KEY,
+			"source" TEXT NOT NULL,
+			"checksum" TEXT NOT NULL,
+			"target_table" TEXT NOT NULL,
+			"rtpLevel" REAL,
+			"srNumber" INTEGER,
+			"rows" INTEGER NOT NULL DEFAULT 0,
+			"status" TEXT NOT NULL,
+			"error" TEXT,
+			"started_at" TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+			"completed_at" TIMESTAMP,
+			UNIQUE ("source", "target_table")
+		);
+	`, importManifestTable)
+	if _, err := db.DB.Exec(query); err != nil {
+		return fmt.Errorf("创建导入清单表失败: %v", err)
+	}
+	return nil
+}
+
+// manifestCompleted 源文件是否已以相同校验和导入完成
+func manifestCompleted(db *Database, src importSource) (bool, error) {
+	var checksum, status string
+	query := fmt.Sprintf(`SELECT "checksum", "status" FROM "%s" WHERE "source" = $1 AND "target_table" = $2`, importManifestTable)
+	err := db.DB.QueryRow(query, src.Source, src.TargetTable).Scan(&checksum, &status)
+	if err == sql.ErrNoRows {
+		return false, nil
+	}
+	if err != nil {
+		return false, fmt.Errorf("查询导入清单失败: %v", err)
+	}
+	return status == manifestStatusCompleted && checksum == src.Checksum, nil
+}
+
+// markManifestStarted 在导入开始前（事务外）登记文件，失败时清单中仍保留记录
+func markManifestStarted(db *Database, src importSource) error {
+	query := fmt.Sprintf(`
+		INSERT INTO "%s" ("source", "checksum", "target_table", "status", "started_at")
+		VALUES ($1, $2, $3, $4, CURRENT_TIMESTAMP)
+		ON CONFLICT ("source", "target_table") DO UPDATE SET
+			"checksum" = EXCLUDED."checksum",
+			"status" = EXCLUDED."status",
+			"rows" = 0,
+			"error" = NULL,
+			"started_at" = EXCLUDED."started_at",
+			"completed_at" = NULL
+	`, importManifestTable)
+	if _, err := db.DB.Exec(query, src.Source, src.Checksum, src.TargetTable, manifestStatusRunning); err != nil {
+		return fmt.Errorf("登记导入清单失败: %v", err)
+	}
+	return nil
+}
+
+// markManifestCompleted 在写入数据的同一事务中标记完成，数据与清单状态同时提交或回滚
+func markManifestCompleted(tx *sql.Tx, src importSource, slice resultSlice, rows int) error {
+	query := fmt.Sprintf(`
+		UPDATE "%s" SET "status" = $3, "rtpLevel" = $4, "srNumber" = $5, "rows" = $6, "completed_at" = CURRENT_TIMESTAMP
+		WHERE "source" = $1 AND "target_table" = $2
+	`, importManifestTable)
+	if _, err := tx.Exec(query, src.Source, src.TargetTable, manifestStatusCompleted, slice.RtpLevel, slice.SrNumber, rows); err != nil {
+		return fmt.Errorf("更新导入清单失败: %v", err)
+	}
+	return nil
+}
+
+// markManifestFinished 在事务外记录失败或跳过的结果，errMsg 为空表示无错误信息
+func markManifestFinished(db *Database, src importSource, status string, errMsg string) {
+	query := fmt.Sprintf(`
+		UPDATE "%s" SET "status" = $3, "error" = NULLIF($4, ''), "completed_at" = CURRENT_TIMESTAMP
+		WHERE "source" = $1 AND "target_table" = $2
+	`, importManifestTable)
+	if _, err := db.DB.Exec(query, src.Source, src.TargetTable, status, errMsg); err != nil {
+		fmt.Printf("⚠️ 更新导入清单失败: %v\n", err)
+	}
+}
+
+// fileSHA256 计算本地文件的 sha256 校验和
+func fileSHA256(path string) (string, error) {
+	fh, err := os.Open(path)
+	if err != nil {
+		return "", err
+	}
+	defer fh.Close()
+	return readerSHA256(fh)
+}
+
+// readerSHA256 读取全部内容并计算 sha256 校验和（sha256:<hex>）
+func readerSHA256(r io.Reader) (string, error) {
+	h := sha256.New()
+	if _, err := io.Copy(h, r); err != nil {
+		return "", fmt.Errorf("计算校验和失败: %v", err)
+	}
+	return "sha256:" + hex.EncodeToString(h.Sum(nil)), nil
+}
+
+// localImportSource 本地结果文件的清单标识
+func localImportSource(path, tableName string) (importSource, error) {
+	checksum, err := fileSHA256(path)
+	if err != nil {
+		return importSource{}, err
+	}
+	return importSource{Source: path, Checksum: checksum, TargetTable: tableName}, nil
+}
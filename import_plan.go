@@ -0,0 +1,203 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"sync"
+)
+
+// importPlan --dry-run 模式下累计的导入计划
+type importPlan struct {
+	Files     int            // 计划写入的文件数
+	Rows      int            // 计划写入的记录数
+	TotalBet  Money          // 计划写入记录的总投注
+	TotalWin  Money          // 计划写入记录的总中奖
+	Conflicts int            // 与目标表已有切片冲突的文件数
+	Unchanged int            // 导入清单中已完成且校验和未变化、将跳过的文件数
+	Replaced  int            // --on-conflict replace 时将被删除替换的已有记录数
+	Slices    []plannedSlice // 计划写入的切片
+}
+
+// plannedSlice 计划写入的一个切片
+type plannedSlice struct {
+	resultSlice
+	Rows     int   // 写入条数
+	TotalBet Money // 写入记录的总投注
+	TotalWin Money // 写入记录的总中奖
+	Replaced int   // 被替换的已有记录数
+}
+
+// add 合并另一份计划
+func (p *importPlan) add(o importPlan) {
+	p.Files += o.Files
+	p.Rows += o.Rows
+	p.TotalBet += o.TotalBet
+	p.TotalWin += o.TotalWin
+	p.Conflicts += o.Conflicts
+	p.Unchanged += o.Unchanged
+	p.Replaced += o.Replaced
+	p.Slices = append(p.Slices, o.Slices...)
+}
+
+// importPlanTotals 多个导入会话共用的计划汇总
+type importPlanTotals struct {
+	mu   sync.Mutex
+	plan importPlan
+}
+
+// add 合并一个会话的计划
+func (t *importPlanTotals) add(p importPlan) {
+	t.mu.Lock()
+	t.plan.add(p)
+	t.mu.Unlock()
+}
+
+// Plan 当前的计划汇总
+func (t *importPlanTotals) Plan() importPlan {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return t.plan
+}
+
+// tableExists 目标库中是否已存在指定表（区分大小写）
+func tableExists(db *Database, tableName string) (bool, error) {
+	var exists bool
+	if err := db.DB.QueryRow(`SELECT to_regclass($1) IS NOT NULL`, `"`+tableName+`"`).Scan(&exists); err != nil {
+		return false, fmt.Errorf("检查表 %s 是否存在失败: %v", tableName, err)
+	}
+	return exists, nil
+}
+
+// prepareImportTarget 创建目标表与导入清单表；--dry-run 时只报告目标表状态，不做任何写入
+func prepareImportTarget(db *Database, tableName string, options ImportOptions) error {
+	if !options.DryRun {
+		if err := ensureResultTable(db, tableName); err != nil {
+			return fmt.Errorf("创建目标表失败: %v", err)
+		}
+		return ensureImportManifest(db)
+	}
+
+	exists, err := tableExists(db, tableName)
+	if err != nil {
+		return err
+	}
+	if exists {
+		fmt.Printf("🧪 [dry-run] 目标表: %s（已存在）\n", tableName)
+	} else {
+		fmt.Printf("🧪 [dry-run] 目标表: %s（不存在，实际导入时将创建）\n", tableName)
+	}
+	fmt.Printf("🧪 [dry-run] 冲突策略: %s, 事务范围: %s\n", options.Policy, options.Scope)
+	return nil
+}
+
+// planFile --dry-run：完整读取文件统计记录数与总投注、总中奖，并报告与目标表已有数据、导入清单的关系
+func (s *importSession) planFile(src importSource, open func() (io.ReadCloser, error), target func(*ResultFileHeader) resultSlice) (int, bool, error) {
+	manifestExists, err := tableExists(s.db, importManifestTable)
+	if err != nil {
+		return 0, false, err
+	}
+	if manifestExists {
+		done, err := manifestCompleted(s.db, src)
+		if err != nil {
+			return 0, false, err
+		}
+		if done {
+			s.plan.Unchanged++
+			fmt.Printf("  ⏭️  [计划] %s: 导入清单中已完成且校验和未变化，将跳过\n", src.Source)
+			return 0, true, nil
+		}
+	}
+
+	r, err := open()
+	if err != nil {
+		return 0, false, err
+	}
+	defer r.Close()
+
+	dec := json.NewDecoder(r)
+	header, err := readResultHeader(dec)
+	if err != nil {
+		return 0, false, err
+	}
+	slice := target(header)
+	rows := 0
+	var totalBet, totalWin Money
+	for dec.More() {
+		var row ResultRow
+		if err := dec.Decode(&row); err != nil {
+			return rows, false, fmt.Errorf("解析数据项 %d 失败: %v", rows+1, err)
+		}
+		rows++
+		totalBet += row.TB
+		totalWin += row.AW
+	}
+	if err := readResultTrailer(dec); err != nil {
+		return rows, false, err
+	}
+
+	mapping := ""
+	if slice.RtpLevel != float64(header.RtpLevel) {
+		mapping = fmt.Sprintf(" (文件档位 %d → %g)", header.RtpLevel, slice.RtpLevel)
+	}
+	fmt.Printf("  📝 [计划] %s → %s: rtpLevel=%g%s, srNumber=%d, 记录 %d 条, 总投注 %s, 总中奖 %s, RTP %.6f\n",
+		src.Source, slice.TableName, slice.RtpLevel, mapping, slice.SrNumber, rows, totalBet, totalWin, totalWin.Ratio(totalBet))
+
+	existing := 0
+	targetExists, err := tableExists(s.db, slice.TableName)
+	if err != nil {
+		return rows, false, err
+	}
+	if targetExists {
+		if existing, err = countSliceRows(s.db.DB, slice.TableName, slice.RtpLevel, slice.SrNumber); err != nil {
+			return rows, false, err
+		}
+	}
+	if existing == 0 {
+		s.plan.Files++
+		s.plan.Rows += rows
+		s.plan.TotalBet += totalBet
+		s.plan.TotalWin += totalWin
+		s.plan.Slices = append(s.plan.Slices, plannedSlice{resultSlice: slice, Rows: rows, TotalBet: totalBet, TotalWin: totalWin})
+		return rows, false, nil
+	}
+
+	s.plan.Conflicts++
+	replaced := 0
+	switch s.options.Policy {
+	case ImportPolicySkipExisting:
+		fmt.Printf("     ⏭️  目标表已有该切片 %d 条记录，将跳过\n", existing)
+		return rows, true, nil
+	case ImportPolicyReplace:
+		fmt.Printf("     ♻️  目标表已有该切片 %d 条记录，将替换为 %d 条\n", existing, rows)
+		replaced = existing
+	default:
+		fmt.Printf("     ❌ 目标表已有该切片 %d 条记录，实际导入将报错（可使用 --on-conflict skip-existing/replace）\n", existing)
+	}
+	s.plan.Files++
+	s.plan.Rows += rows
+	s.plan.TotalBet += totalBet
+	s.plan.TotalWin += totalWin
+	s.plan.Replaced += replaced
+	s.plan.Slices = append(s.plan.Slices, plannedSlice{resultSlice: slice, Rows: rows, TotalBet: totalBet, TotalWin: totalWin, Replaced: replaced})
+	return rows, false, nil
+}
+
+// printPlanSummary 输出并清空当前累计的导入计划
+func (s *importSession) printPlanSummary() {
+	p := s.plan
+	fmt.Printf("\n🧪 [dry-run] 计划写入 %d 个文件，共 %d 条记录；%d 个文件与已有切片冲突，%d 个文件按导入清单跳过\n",
+		p.Files, p.Rows, p.Conflicts, p.Unchanged)
+	fmt.Printf("🧪 [dry-run] 总投注 %s，总中奖 %s，RTP %.6f\n", p.TotalBet, p.TotalWin, p.TotalWin.Ratio(p.TotalBet))
+	if p.Replaced > 0 {
+		fmt.Printf("🧪 [dry-run] 将删除并替换已有记录 %d 条\n", p.Replaced)
+	}
+	if p.Conflicts > 0 && s.options.Policy == ImportPolicyFail {
+		fmt.Println("🧪 [dry-run] 当前冲突策略为 fail，实际导入会在第一个冲突的文件处失败")
+	}
+	fmt.Println("🧪 [dry-run] 未写入任何数据")
+	if s.options.Plan != nil {
+		s.options.Plan.add(p)
+	}
+	s.plan = importPlan{}
+}
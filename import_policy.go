@@ -0,0 +1,130 @@
+package main
+
+import (
+	"database/sql"
+	"fmt"
+	"os"
+	"strings"
+)
+
+// ImportPolicy 目标表中已存在同一 (rtpLevel, srNumber) 切片时的处理策略
+type ImportPolicy string
+
+const (
+	// ImportPolicyFail 切片已存在时报错退出（默认）
+	ImportPolicyFail ImportPolicy = "fail"
+	// ImportPolicySkipExisting 切片已存在时跳过该文件
+	ImportPolicySkipExisting ImportPolicy = "skip-existing"
+	// ImportPolicyReplace 在同一事务中删除已有切片并写入新数据
+	ImportPolicyReplace ImportPolicy = "replace"
+)
+
+// ImportScope 导入事务的范围
+type ImportScope string
+
+const (
+	// ImportScopeFile 每个文件一个事务（默认）
+	ImportScopeFile ImportScope = "file"
+	// ImportScopeGame 整个游戏目录一个事务，任一文件失败则全部回滚
+	ImportScopeGame ImportScope = "game"
+)
+
+// ImportOptions 导入命令的公共选项
+type ImportOptions struct {
+	Policy ImportPolicy
+	Scope  ImportScope
+	DryRun bool // 只输出导入计划，不写入任何数据
+	// Plan dry-run 时累计本次运行全部会话的计划，受保护环境确认写入前使用；为 nil 时不累计
+	Plan *importPlanTotals
+}
+
+// ParseImportPolicy 解析 --on-conflict 参数，空值返回默认策略 fail
+func ParseImportPolicy(s string) (ImportPolicy, error) {
+	switch ImportPolicy(strings.TrimSpace(s)) {
+	case "", ImportPolicyFail:
+		return ImportPolicyFail, nil
+	case ImportPolicySkipExisting:
+		return ImportPolicySkipExisting, nil
+	case ImportPolicyReplace:
+		return ImportPolicyReplace, nil
+	}
+	return "", fmt.Errorf("无效的冲突策略: %s（可选 fail/skip-existing/replace）", s)
+}
+
+// ParseImportScope 解析 --atomic 参数，空值返回默认范围 file
+func ParseImportScope(s string) (ImportScope, error) {
+	switch ImportScope(strings.TrimSpace(s)) {
+	case "", ImportScopeFile:
+		return ImportScopeFile, nil
+	case ImportScopeGame:
+		return ImportScopeGame, nil
+	}
+	return "", fmt.Errorf("无效的事务范围: %s（可选 file/game）", s)
+}
+
+// ensureSliceUniqueIndex 创建 (rtpLevel, srNumber, srId) 唯一索引；表中已有重复数据时给出清理提示
+func ensureSliceUniqueIndex(db *Database, tableName string) error {
+	query := fmt.Sprintf(`CREATE UNIQUE INDEX IF NOT EXISTS "%s_rtpLevel_srNumber_srId_uniq" ON "%s" ("rtpLevel", "srNumber", "srId")`, tableName, tableName)
+	if _, err := db.DB.Exec(query); err != nil {
+		return fmt.Errorf("创建唯一索引失败（表 %s 中可能已存在重复的 rtpLevel/srNumber/srId，请先清理重复数据）: %v", tableName, err)
+	}
+	return nil
+}
+
+// rowQuerier *sql.DB 与 *sql.Tx 共有的单行查询接口
+type rowQuerier interface {
+	QueryRow(query string, args ...interface{}) *sql.Row
+}
+
+// countSliceRows 统计目标表中某个 (rtpLevel, srNumber) 切片已有的记录数
+func countSliceRows(q rowQuerier, tableName string, rtpLevel float64, srNumber int) (int, error) {
+	var existing int
+	countSQL := fmt.Sprintf(`SELECT count(1) FROM "%s" WHERE "rtpLevel" = $1::real AND "srNumber" = $2`, tableName)
+	if err := q.QueryRow(countSQL, rtpLevel, srNumber).Scan(&existing); err != nil {
+		return 0, fmt.Errorf("检查已有切片失败: %v", err)
+	}
+	return existing, nil
+}
+
+// prepareSlice 在导入事务中按策略处理已存在的 (rtpLevel, srNumber) 切片
+// 返回 true 表示切片已存在且策略为 skip-existing，调用方应跳过该文件
+// 策略为 replace 时已有切片在同一事务中删除，新数据提交前旧数据对外保持可见
+func prepareSlice(tx *sql.Tx, tableName string, rtpLevel float64, srNumber int, policy ImportPolicy) (bool, error) {
+	existing, err := countSliceRows(tx, tableName, rtpLevel, srNumber)
+	if err != nil {
+		return false, err
+	}
+	if existing == 0 {
+		return false, nil
+	}
+
+	switch policy {
+	case ImportPolicySkipExisting:
+		fmt.Printf("  ⏭️  切片 rtpLevel=%g, srNumber=%d 已存在 %d 条记录，跳过\n", rtpLevel, srNumber, existing)
+		return true, nil
+	case ImportPolicyReplace:
+		deleteSQL := fmt.Sprintf(`DELETE FROM "%s" WHERE "rtpLevel" = $1::real AND "srNumber" = $2`, tableName)
+		if _, err := tx.Exec(deleteSQL, rtpLevel, srNumber); err != nil {
+			return false, fmt.Errorf("删除已有切片失败: %v", err)
+		}
+		fmt.Printf("  ♻️  切片 rtpLevel=%g, srNumber=%d 已存在 %d 条记录，将在同一事务中替换\n", rtpLevel, srNumber, existing)
+		return false, nil
+	default:
+		return false, fmt.Errorf("切片 rtpLevel=%g, srNumber=%d 已存在 %d 条记录（使用 --on-conflict skip-existing 跳过或 --on-conflict replace 替换）", rtpLevel, srNumber, existing)
+	}
+}
+
+// mustImportOptions 从命令选项中解析导入选项，解析失败直接退出
+func mustImportOptions(a *cliArgs) ImportOptions {
+	policy, err := ParseImportPolicy(a.OnConflict)
+	if err != nil {
+		fmt.Printf("❌ %v\n", err)
+		os.Exit(1)
+	}
+	scope, err := ParseImportScope(a.Atomic)
+	if err != nil {
+		fmt.Printf("❌ %v\n", err)
+		os.Exit(1)
+	}
+	return ImportOptions{Policy: policy, Scope: scope, DryRun: a.DryRun}
+}
@@ -1,16 +1,11 @@
 package main
 
 import (
-	"bufio"
-	"encoding/json"
 	"fmt"
 	"io"
 	"io/fs"
 	"log"
-	"math"
-	"os"
 	"path/filepath"
-	"regexp"
 	"sort"
 	"strconv"
 	"strings"
@@ -20,27 +15,30 @@ import (
 
 // JSONImporter JSON文件导入器
 type JSONImporter struct {
-	db     *Database
-	config *Config
+	db      *Database
+	config  *Config
+	options ImportOptions
 }
 
 // S3Importer S3文件导入器
 type S3Importer struct {
 	db       *Database
 	config   *Config
+	options  ImportOptions
 	s3Client *S3Client
 }
 
 // NewJSONImporter 创建新的JSON导入器
-func NewJSONImporter(db *Database, config *Config) *JSONImporter {
+func NewJSONImporter(db *Database, config *Config, options ImportOptions) *JSONImporter {
 	return &JSONImporter{
-		db:     db,
-		config: config,
+		db:      db,
+		config:  config,
+		options: options,
 	}
 }
 
 // NewS3Importer 创建新的S3导入器
-func NewS3Importer(db *Database, config *Config) (*S3Importer, error) {
+func NewS3Importer(db *Database, config *Config, options ImportOptions) (*S3Importer, error) {
 	s3Client, err := NewS3Client(config)
 	if err != nil {
 		return nil, err
@@ -49,6 +47,7 @@ func NewS3Importer(db *Database, config *Config) (*S3Importer, error) {
 	return &S3Importer{
 		db:       db,
 		config:   config,
+		options:  options,
 		s3Client: s3Client,
 	}, nil
 }
@@ -65,7 +64,7 @@ type FileInfo struct {
 // ImportAllFiles 导入所有JSON文件
 func (ji *JSONImporter) ImportAllFiles(fileLevelId string) error {
 	// 读取目录：按游戏ID分目录，例如 output/93
-	outputDir := filepath.Join("output", fmt.Sprintf("%d", ji.config.Game.ID))
+	outputDir := filepath.Join(outputRoot, fmt.Sprintf("%d", ji.config.Game.ID))
 	fmt.Printf("📂 导入目录: %s\n", outputDir)
 
 	// 获取所有JSON文件
@@ -105,18 +104,26 @@ func (ji *JSONImporter) ImportAllFiles(fileLevelId string) error {
 
 	// 创建目标表
 	tableName := fmt.Sprintf("%s%d", ji.config.Tables.OutputTablePrefix, ji.config.Game.ID)
-	if err := ji.createTargetTable(tableName); err != nil {
-		return fmt.Errorf("创建目标表失败: %v", err)
+	if err := prepareImportTarget(ji.db, tableName, ji.options); err != nil {
+		return err
 	}
 
 	// 逐个导入文件
+	session := newImportSession(ji.db, ji.config, ji.options)
+	if err := session.Begin(); err != nil {
+		return err
+	}
+	defer session.Rollback()
 	for _, file := range files {
 		fmt.Printf("\n🔄 正在导入文件: %s\n", file.Name)
-		if err := ji.importFile(file, tableName); err != nil {
+		if err := ji.importFile(session, file, tableName); err != nil {
 			return fmt.Errorf("导入文件 %s 失败: %v", file.Name, err)
 		}
 		fmt.Printf("✅ 文件 %s 导入完成\n", file.Name)
 	}
+	if err := session.Commit(); err != nil {
+		return err
+	}
 
 	fmt.Printf("\n🎉 所有文件导入完成！\n")
 	return nil
@@ -124,7 +131,7 @@ func (ji *JSONImporter) ImportAllFiles(fileLevelId string) error {
 
 // ImportAllFilesWithGameId 支持指定 gameId 与 level 过滤
 func (ji *JSONImporter) ImportAllFilesWithGameId(gameId int, levelFilter string) error {
-	outputDir := filepath.Join("output", fmt.Sprintf("%d", gameId))
+	outputDir := filepath.Join(outputRoot, fmt.Sprintf("%d", gameId))
 	fmt.Printf("📂 导入目录: %s\n", outputDir)
 
 	files, err := ji.getJSONFiles(outputDir)
@@ -136,13 +143,7 @@ func (ji *JSONImporter) ImportAllFilesWithGameId(gameId int, levelFilter string)
 	}
 
 	if levelFilter != "" {
-		var filtered []FileInfo
-		prefix := fmt.Sprintf("GameResults_%s_", levelFilter)
-		for _, f := range files {
-			if strings.HasPrefix(f.Name, prefix) {
-				filtered = append(filtered, f)
-			}
-		}
+		filtered := ji.filterFilesByFileLevelId(files, levelFilter)
 		if len(filtered) == 0 {
 			fmt.Printf("❌ 未找到fileLevelId为 %s 的JSON文件\n", levelFilter)
 			for _, f := range files {
@@ -161,16 +162,24 @@ func (ji *JSONImporter) ImportAllFilesWithGameId(gameId int, levelFilter string)
 	}
 
 	tableName := fmt.Sprintf("%s%d", ji.config.Tables.OutputTablePrefix, gameId)
-	if err := ji.createTargetTable(tableName); err != nil {
-		return fmt.Errorf("创建目标表失败: %v", err)
+	if err := prepareImportTarget(ji.db, tableName, ji.options); err != nil {
+		return err
 	}
+	session := newImportSession(ji.db, ji.config, ji.options)
+	if err := session.Begin(); err != nil {
+		return err
+	}
+	defer session.Rollback()
 	for _, f := range files {
 		fmt.Printf("\n🔄 正在导入文件: %s\n", f.Name)
-		if err := ji.importFile(f, tableName); err != nil {
+		if err := ji.importFile(session, f, tableName); err != nil {
 			return fmt.Errorf("导入文件 %s 失败: %v", f.Name, err)
 		}
 		fmt.Printf("✅ 文件 %s 导入完成\n", f.Name)
 	}
+	if err := session.Commit(); err != nil {
+		return err
+	}
 	fmt.Printf("\n🎉 所有文件导入完成！\n")
 	return nil
 }
@@ -178,7 +187,7 @@ func (ji *JSONImporter) ImportAllFilesWithGameId(gameId int, levelFilter string)
 // filterFilesByFileLevelId 根据fileLevelId过滤文件
 func (ji *JSONImporter) filterFilesByFileLevelId(files []FileInfo, fileLevelId string) []FileInfo {
 	var filteredFiles []FileInfo
-	prefix := fmt.Sprintf("GameResults_%s_", fileLevelId)
+	prefix := fmt.Sprintf("%s%s_", ji.config.Tables.OutputTablePrefix, fileLevelId)
 
 	for _, file := range files {
 		if strings.HasPrefix(file.Name, prefix) {
@@ -192,6 +201,7 @@ func (ji *JSONImporter) filterFilesByFileLevelId(files []FileInfo, fileLevelId s
 // getJSONFiles 获取指定目录下的所有JSON文件
 func (ji *JSONImporter) getJSONFiles(dir string) ([]FileInfo, error) {
 	var files []FileInfo
+	re := resultFileRegexp(ji.config)
 
 	// 遍历目录
 	err := filepath.WalkDir(dir, func(path string, d fs.DirEntry, err error) error {
@@ -204,22 +214,18 @@ func (ji *JSONImporter) getJSONFiles(dir string) ([]FileInfo, error) {
 			return nil
 		}
 
-		// 检查是否是JSON文件
-		if !strings.HasSuffix(strings.ToLower(d.Name()), ".json") {
+		// 检查是否是JSON文件（.json 或 .json.gz）
+		if !isResultFileName(d.Name()) {
 			return nil
 		}
 
-		// 解析文件名：GameResults_15_1.json -> RtpLevel=15, TestNum=1
-		re := regexp.MustCompile(`GameResults_(\d+)_(\d+)\.json`)
-		matches := re.FindStringSubmatch(d.Name())
-		if len(matches) != 3 {
+		// 解析文件名：<output_table_prefix>15_1.json[.gz] -> RtpLevel=15, TestNum=1
+		rtpLevel, testNum, ok := parseResultFileName(re, d.Name())
+		if !ok {
 			log.Printf("⚠️ 跳过不符合命名规则的文件: %s", d.Name())
 			return nil
 		}
 
-		rtpLevel, _ := strconv.Atoi(matches[1])
-		testNum, _ := strconv.Atoi(matches[2])
-
 		// 创建排序键，确保正确的处理顺序
 		sortKey := fmt.Sprintf("%02d_%02d", rtpLevel, testNum)
 
@@ -242,319 +248,23 @@ func (ji *JSONImporter) getJSONFiles(dir string) ([]FileInfo, error) {
 	return files, nil
 }
 
-// createTargetTable 创建目标数据表
-func (ji *JSONImporter) createTargetTable(tableName string) error {
-	query := fmt.Sprintf(`
-		CREATE TABLE IF NOT EXISTS "%s" (
-			"id" SERIAL PRIMARY KEY,
-			"rtpLevel" REAL NOT NULL,
-			"srNumber" INTEGER NOT NULL,
-			"srId" SERIAL NOT NULL,
-			"bet" NUMERIC NOT NULL,
-			"win" NUMERIC NOT NULL,
-			"detail" JSONB,
-			"created_at" TIMESTAMP DEFAULT CURRENT_TIMESTAMP
-		);
-	`, tableName)
-
-	// 执行创建表语句
-	_, err := ji.db.DB.Exec(query)
-	if err != nil {
-		return fmt.Errorf("创建表失败: %v", err)
-	}
-
-	// 创建索引
-	indexQueries := []string{
-		fmt.Sprintf(`CREATE INDEX IF NOT EXISTS "%s_rtpLevel_idx" ON "%s" ("rtpLevel")`, tableName, tableName),
-		fmt.Sprintf(`CREATE INDEX IF NOT EXISTS "%s_srNumber_idx" ON "%s" ("srNumber")`, tableName, tableName),
-		fmt.Sprintf(`CREATE INDEX IF NOT EXISTS "%s_srId_idx" ON "%s" ("srId")`, tableName, tableName),
-		fmt.Sprintf(`CREATE INDEX IF NOT EXISTS "%s_rtpLevel_srNumber_idx" ON "%s" ("rtpLevel", "srNumber")`, tableName, tableName),
-		fmt.Sprintf(`CREATE INDEX IF NOT EXISTS "%s_rtpLevel_srNumber_srId_idx" ON "%s" ("rtpLevel", "srNumber", "srId")`, tableName, tableName),
-		fmt.Sprintf(`CREATE INDEX IF NOT EXISTS "%s_detail_gin_idx" ON "%s" USING GIN ("detail")`, tableName, tableName),
-	}
-
-	for _, indexSQL := range indexQueries {
-		_, err := ji.db.DB.Exec(indexSQL)
-		if err != nil {
-			return fmt.Errorf("创建索引失败: %v", err)
-		}
-	}
-
-	log.Printf("✅ 成功创建目标表: %s", tableName)
-	return nil
-}
-
-// importFile 流式导入JSON文件
-func (ji *JSONImporter) importFile(file FileInfo, tableName string) error {
-	// 打开文件
-	fileHandle, err := os.Open(file.Path)
-	if err != nil {
-		return fmt.Errorf("打开JSON文件失败: %v", err)
-	}
-	defer fileHandle.Close()
-
-	// 读取文件头部信息（rtpLevel, srNumber, metadata等）
-	header, err := ji.readFileHeader(file.Path)
-	if err != nil {
-		return fmt.Errorf("读取文件头部失败: %v", err)
-	}
-
-	fmt.Printf("  📊 文件包含 %d 条记录\n", header.totalRecords)
-
-	// 流式处理数据
-	batchSize := ji.config.Settings.BatchSize
-	var batch []map[string]interface{}
-	batchCount := 0
-	totalProcessed := 0
-
-	// 跳过到数据数组的开始位置，并返回新的读取器
-	newReader, err := ji.skipToDataArray(fileHandle)
-	if err != nil {
-		return fmt.Errorf("定位数据数组失败: %v", err)
-	}
-
-	// 开始流式解析
-	decoder := json.NewDecoder(newReader)
-
-	// 读取数组开始标记 '['
-	token, err := decoder.Token()
-	if err != nil {
-		return fmt.Errorf("读取数组开始标记失败: %v", err)
-	}
-	if token != json.Delim('[') {
-		return fmt.Errorf("期望数组开始标记 '['，但得到 %v", token)
-	}
-
-	// 逐条读取数据
-	for decoder.More() {
-		var item map[string]interface{}
-		if err := decoder.Decode(&item); err != nil {
-			return fmt.Errorf("解析数据项失败: %v", err)
-		}
-
-		batch = append(batch, item)
-		totalProcessed++
-
-		// 当批次满了或者到达文件末尾时，插入数据库
-		if len(batch) >= batchSize {
-			batchCount++
-			fmt.Printf("  🔄 处理批次 %d (记录 %d-%d)\n", batchCount, totalProcessed-len(batch)+1, totalProcessed)
-			fmt.Print("导入档位: ", file.RtpLevel)
-			if err := ji.insertBatch(batch, tableName, file.RtpLevel, file.TestNum, batchCount); err != nil {
-				return fmt.Errorf("插入批次 %d 失败: %v", batchCount, err)
-			}
-
-			// 清空批次
-			batch = batch[:0]
-		}
-	}
-
-	// 处理最后一批（可能不足batchSize）
-	if len(batch) > 0 {
-		batchCount++
-		fmt.Printf("  🔄 处理最后批次 %d (记录 %d-%d)\n", batchCount, totalProcessed-len(batch)+1, totalProcessed)
-
-		if err := ji.insertBatch(batch, tableName, file.RtpLevel, file.TestNum, batchCount); err != nil {
-			return fmt.Errorf("插入最后批次失败: %v", err)
-		}
-	}
-
-	// 读取数组结束标记 ']'
-	token, err = decoder.Token()
-	if err != nil {
-		return fmt.Errorf("读取数组结束标记失败: %v", err)
-	}
-	if token != json.Delim(']') {
-		return fmt.Errorf("期望数组结束标记 ']'，但得到 %v", token)
-	}
-
-	fmt.Printf("  ✅ 总共处理 %d 条记录，分 %d 批次\n", totalProcessed, batchCount)
-	return nil
-}
-
-// FileHeader 文件头部信息
-type FileHeader struct {
-	rtpLevel     int
-	srNumber     int
-	totalRecords int
-}
-
-// readFileHeader 读取文件头部信息
-func (ji *JSONImporter) readFileHeader(filePath string) (*FileHeader, error) {
-	// 打开文件来读取头部
-	fileHandle, err := os.Open(filePath)
-	if err != nil {
-		return nil, err
-	}
-	defer fileHandle.Close()
-
-	// 创建新的读取器
-	headerReader := bufio.NewReader(fileHandle)
-
-	// 逐行读取，找到rtpLevel, srNumber等信息
-	var header FileHeader
-	var line string
-
-	for {
-		line, err = headerReader.ReadString('\n')
-		if err != nil {
-			break
-		}
-
-		line = strings.TrimSpace(line)
-
-		// 查找rtpLevel
-		if strings.Contains(line, `"rtpLevel"`) {
-			if idx := strings.Index(line, ":"); idx != -1 {
-				valueStr := strings.TrimSpace(line[idx+1:])
-				valueStr = strings.TrimRight(valueStr, ",")
-				if value, err := strconv.Atoi(valueStr); err == nil {
-					header.rtpLevel = value
-				}
-			}
-		}
-
-		// 查找srNumber
-		if strings.Contains(line, `"srNumber"`) {
-			if idx := strings.Index(line, ":"); idx != -1 {
-				valueStr := strings.TrimSpace(line[idx+1:])
-				valueStr = strings.TrimRight(valueStr, ",")
-				if value, err := strconv.Atoi(valueStr); err == nil {
-					header.srNumber = value
-				}
-			}
-		}
-
-		// 如果找到了数据数组的开始，停止读取头部
-		if strings.Contains(line, `"data"`) && strings.Contains(line, "[") {
-			break
-		}
-	}
-
-	// 估算总记录数（通过计算文件大小和平均行长度）
-	fileInfo, err := fileHandle.Stat()
-	if err == nil {
-		// 简单估算：假设每条记录平均200字节
-		header.totalRecords = int(fileInfo.Size() / 200)
-	}
-
-	return &header, nil
-}
-
-// skipToDataArray 跳过到数据数组的开始位置
-func (ji *JSONImporter) skipToDataArray(file *os.File) (*bufio.Reader, error) {
-	// 从文件头开始扫描，定位到 data 数组的 '[' 字符处
-	if _, err := file.Seek(0, io.SeekStart); err != nil {
-		return nil, err
-	}
-
-	r := bufio.NewReader(file)
-	var offset int64 = 0
-	for {
-		line, err := r.ReadString('\n')
-		if len(line) > 0 {
-			if strings.Contains(line, `"data"`) && strings.Contains(line, "[") {
-				idx := strings.Index(line, "[")
-				if idx >= 0 {
-					// 将文件指针定位到 '[' 处
-					if _, err := file.Seek(offset+int64(idx), io.SeekStart); err != nil {
-						return nil, err
-					}
-					return bufio.NewReader(file), nil
-				}
-			}
-			offset += int64(len(line))
-		}
-		if err != nil {
-			if err == io.EOF {
-				break
-			}
-			return nil, err
-		}
-	}
-	return nil, fmt.Errorf("未找到 data 数组的起始位置")
-}
-
-// insertBatch 批量插入数据
-func (ji *JSONImporter) insertBatch(data []map[string]interface{}, tableName string, rtpLevel, testNum int, batchNum int) error {
-	if len(data) == 0 {
-		return nil
-	}
-
-	// 显示当前批次进度
-	fmt.Printf("    🔄 正在处理第 %d 批数据 (%d 条记录)...\n", batchNum, len(data))
-
-	// 开始事务
-	tx, err := ji.db.DB.Begin()
+// importFile 流式导入JSON文件，整个文件通过 COPY 在会话的事务中写入
+func (ji *JSONImporter) importFile(session *importSession, file FileInfo, tableName string) error {
+	src, err := localImportSource(file.Path, tableName)
 	if err != nil {
-		return fmt.Errorf("开始事务失败: %v", err)
+		return fmt.Errorf("读取JSON文件失败: %v", err)
 	}
-	defer tx.Rollback()
-
-	// 准备插入语句
-	query := fmt.Sprintf(`
-		INSERT INTO "%s" ("rtpLevel", "srNumber", "srId", "bet", "win", "detail")
-		VALUES ($1, $2, $3, $4, $5, $6)
-	`, tableName)
-
-	stmt, err := tx.Prepare(query)
+	count, skipped, err := session.ImportFile(src, func() (io.ReadCloser, error) {
+		return openResultFile(file.Path)
+	}, func(h *ResultFileHeader) resultSlice {
+		return resultSlice{TableName: tableName, RtpLevel: float64(h.RtpLevel), SrNumber: h.SrNumber}
+	})
 	if err != nil {
-		return fmt.Errorf("准备语句失败: %v", err)
-	}
-	defer stmt.Close()
-
-	// 计算投注金额
-	// bet := ji.config.Bet.CS * ji.config.Bet.ML * ji.config.Bet.BL
-
-	// 批量插入数据
-	for i, item := range data {
-		// 将gd字段转换为JSON字符串以适配JSONB类型
-		var detailVal interface{}
-		if item["gd"] != nil {
-			// 将gd字段转换为JSON字符串
-			gdJSON, err := json.Marshal(item["gd"])
-			if err != nil {
-				return fmt.Errorf("序列化gd字段失败: %v", err)
-			}
-			detailVal = string(gdJSON)
-		}
-
-		// 精度修正：将win字段四舍五入到2位小数
-		var winValue float64
-		if aw, ok := item["aw"].(float64); ok {
-			// 四舍五入到2位小数，避免浮点数精度问题
-			winValue = math.Round(aw*100) / 100
-		} else {
-			winValue = 0.0
-		}
-
-		var totalBet float64
-		if aw, ok := item["tb"].(float64); ok {
-			// 四舍五入到2位小数，避免浮点数精度问题
-			totalBet = math.Round(aw*100) / 100
-		} else {
-			totalBet = 0.0
-		}
-		rtpLevelVal := float64(rtpLevel)
-		_, err := stmt.Exec(
-			rtpLevelVal, // rtpLevel
-			testNum,     // srNumber
-			i+1,         // srId (从1开始)
-			totalBet,    // bet
-			winValue,    // win (精度修正后)
-			detailVal,   // detail (JSONB)
-		)
-		if err != nil {
-			return fmt.Errorf("插入记录 %d 失败: %v", i+1, err)
-		}
+		return err
 	}
-
-	// 提交事务
-	if err := tx.Commit(); err != nil {
-		return fmt.Errorf("提交事务失败: %v", err)
+	if !skipped && !ji.options.DryRun {
+		fmt.Printf("  ✅ 总共写入 %d 条记录\n", count)
 	}
-
-	fmt.Printf("    ✅ 第 %d 批数据处理完成\n", batchNum)
 	return nil
 }
 
@@ -617,6 +327,13 @@ func (si *S3Importer) ImportS3Files(gameIDs []int, mode string, levelFilter stri
 			file.GameID, file.Key, file.RtpLevel, file.TestNum)
 	}
 
+	// 导入清单在并行处理各游戏之前创建
+	if !si.options.DryRun {
+		if err := ensureImportManifest(si.db); err != nil {
+			return err
+		}
+	}
+
 	// 按游戏ID分组处理
 	gameGroups := make(map[int][]S3FileInfo)
 	for _, file := range allFiles {
@@ -641,7 +358,7 @@ func (si *S3Importer) ImportS3Files(gameIDs []int, mode string, levelFilter stri
 
 			// 创建目标表
 			tableName := fmt.Sprintf("%s%d", si.config.Tables.OutputTablePrefix, gid)
-			if err := si.createS3TargetTable(tableName); err != nil {
+			if err := prepareImportTarget(si.db, tableName, si.options); err != nil {
 				mu.Lock()
 				errors = append(errors, fmt.Errorf("游戏 %d 创建目标表失败: %v", gid, err))
 				mu.Unlock()
@@ -661,6 +378,11 @@ func (si *S3Importer) ImportS3Files(gameIDs []int, mode string, levelFilter stri
 			gameDuration := time.Since(gameStartTime)
 			fmt.Printf("✅ [游戏%d] 所有文件导入完成！(耗时: %v)\n", gid, gameDuration)
 		}(gameID, gameFiles)
+
+		// dry-run 逐个游戏输出计划，避免多个游戏的输出交错
+		if si.options.DryRun {
+			wg.Wait()
+		}
 	}
 
 	// 等待所有游戏处理完成
@@ -746,466 +468,71 @@ func (si *S3Importer) filterS3FilesByLevel(files []S3FileInfo, levelFilter strin
 	return filteredFiles
 }
 
-// importS3File 导入单个S3文件
-func (si *S3Importer) importS3File(file S3FileInfo, tableName string) error {
-	// 下载S3文件内容
-	content, err := si.s3Client.DownloadS3File(file.Key)
-	if err != nil {
-		return fmt.Errorf("下载S3文件失败: %v", err)
-	}
-
-	// 解析JSON数据
-	var gameData []GameResultData
-	if err := json.Unmarshal(content, &gameData); err != nil {
-		return fmt.Errorf("解析JSON数据失败: %v", err)
-	}
-
-	// 批量插入数据库
-	return si.insertBatch(gameData, tableName, file.RtpLevel, file.TestNum)
-}
+// importS3FileStream 流式导入单个S3文件，边下载边解析并通过 COPY 写入
+func (si *S3Importer) importS3FileStream(session *importSession, file S3FileInfo, tableName string) error {
+	fmt.Printf("📊 文件 %s: 大小=%.2fMB\n", file.Key, float64(file.Size)/(1024*1024))
 
-// importS3FileStream 流式导入单个S3文件
-func (si *S3Importer) importS3FileStream(file S3FileInfo, tableName string) error {
-	// 获取S3对象流
-	result, err := si.s3Client.GetObjectStream(file.Key)
-	if err != nil {
-		return fmt.Errorf("获取S3对象流失败: %v", err)
+	// 以 ETag 作为校验和，清单中已完成且未变化的对象不会下载
+	src := importSource{
+		Source:      fmt.Sprintf("s3://%s/%s", si.s3Client.bucket, file.Key),
+		Checksum:    "etag:" + file.ETag,
+		TargetTable: tableName,
 	}
-	defer result.Body.Close()
-
-	// 流式JSON解析
-	decoder := json.NewDecoder(result.Body)
-
-	// 优化批处理大小 - 根据文件大小动态调整
-	batchSize := si.calculateOptimalBatchSize(file.Size)
-	batch := make([]map[string]interface{}, 0, batchSize)
-	batchCount := 0
-	totalRecords := 0
-	globalSrId := 0 // 全局srId计数器，确保整个文件内连续
-
-	fmt.Printf("📊 文件 %s: 大小=%.2fMB, 批次大小=%d\n",
-		file.Key, float64(file.Size)/(1024*1024), batchSize)
-
-	// 流式解析JSON文件结构：{"rtpLevel": 200, "srNumber": 1, "data": [...]}
-	var rtpLevel int
-	var srNumber int
-
-	// 解析文件头部信息
-	for decoder.More() {
-		token, err := decoder.Token()
+	// 下载中断时从已读位置续传，对解析与写入透明
+	var reader *s3RetryReader
+	count, skipped, err := session.ImportFile(src, func() (io.ReadCloser, error) {
+		r, err := si.s3Client.OpenObjectReader(file, s3RetryPolicyFor(si.config))
 		if err != nil {
-			return fmt.Errorf("解析JSON token失败: %v", err)
-		}
-
-		if key, ok := token.(string); ok {
-			switch key {
-			case "rtpLevel":
-				if err := decoder.Decode(&rtpLevel); err != nil {
-					return fmt.Errorf("解析rtpLevel失败: %v", err)
-				}
-			case "srNumber":
-				if err := decoder.Decode(&srNumber); err != nil {
-					return fmt.Errorf("解析srNumber失败: %v", err)
-				}
-			case "data":
-				// 进入数据数组
-				token, err := decoder.Token()
-				if err != nil {
-					return fmt.Errorf("读取data数组开始标记失败: %v", err)
-				}
-				if delim, ok := token.(json.Delim); !ok || delim != '[' {
-					return fmt.Errorf("期望数组开始标记 '['，但得到 %v", token)
-				}
-
-				fmt.Printf("📊 S3文件信息: RTP等级=%d, 测试编号=%d, 开始流式处理数据\n",
-					rtpLevel, srNumber)
-
-				// 流式处理数据数组
-				for decoder.More() {
-					var item map[string]interface{}
-					if err := decoder.Decode(&item); err != nil {
-						return fmt.Errorf("解析数据项失败: %v", err)
-					}
-
-					batch = append(batch, item)
-					totalRecords++
-
-					// 达到批次大小时插入数据库
-					if len(batch) >= batchSize {
-						batchCount++
-						fmt.Printf("  🔄 处理批次 %d (记录 %d-%d)\n", batchCount, totalRecords-len(batch)+1, totalRecords)
-						if err := si.insertS3Batch(batch, tableName, rtpLevel, srNumber, batchCount, file.Mode, &globalSrId); err != nil {
-							return fmt.Errorf("批量插入失败: %v", err)
-						}
-						batch = batch[:0] // 清空批次
-					}
-				}
-
-				// 读取数组结束标记
-				token, err = decoder.Token()
-				if err != nil {
-					return fmt.Errorf("读取数组结束标记失败: %v", err)
-				}
-				if delim, ok := token.(json.Delim); !ok || delim != ']' {
-					return fmt.Errorf("期望数组结束标记 ']'，但得到 %v", token)
-				}
-				break
-			}
-		}
-	}
-
-	// 插入剩余数据
-	if len(batch) > 0 {
-		batchCount++
-		fmt.Printf("  🔄 处理最后批次 %d (记录 %d-%d)\n", batchCount, totalRecords-len(batch)+1, totalRecords)
-		if err := si.insertS3Batch(batch, tableName, rtpLevel, srNumber, batchCount, file.Mode, &globalSrId); err != nil {
-			return fmt.Errorf("批量插入剩余数据失败: %v", err)
+			return nil, err
 		}
-	}
-
-	fmt.Printf("  ✅ 总共处理 %d 条记录，分 %d 批次\n", totalRecords, batchCount)
-	return nil
-}
-
-// insertBatch 批量插入数据到数据库 - 优化版本
-func (si *S3Importer) insertBatch(data []GameResultData, tableName string, rtpLevel int, testNum int) error {
-	if len(data) == 0 {
-		return nil
-	}
-
-	// 使用批量插入SQL - 优化版本
-	query := fmt.Sprintf(`
-		INSERT INTO "%s" (rtpLevel, srNumber, srId, bet, win, detail) 
-		VALUES %s
-	`, tableName, si.generatePlaceholders(len(data)))
-
-	// 准备参数
-	args := make([]interface{}, 0, len(data)*6)
-	for i, item := range data {
-		args = append(args, rtpLevel, testNum, i+1, item.TB, item.AW, item.GD)
-	}
-
-	// 开始事务 - 使用带重试机制的事务开始
-	tx, err := si.db.BeginWithRetry()
-	if err != nil {
-		return fmt.Errorf("开始事务失败: %v", err)
-	}
-	defer tx.Rollback()
-
-	// 执行批量插入
-	_, err = tx.Exec(query, args...)
-	if err != nil {
-		return fmt.Errorf("批量插入失败: %v", err)
-	}
-
-	// 提交事务
-	if err := tx.Commit(); err != nil {
-		return fmt.Errorf("提交事务失败: %v", err)
-	}
-
-	return nil
-}
-
-// insertS3Batch 批量插入S3数据到数据库
-func (si *S3Importer) insertS3Batch(data []map[string]interface{}, tableName string, rtpLevel int, testNum int, batchNum int, mode string, globalSrId *int) error {
-	if len(data) == 0 {
-		return nil
-	}
-
-	// 显示当前批次进度
-	fmt.Printf("    🔄 正在处理第 %d 批数据 (%d 条记录)...\n", batchNum, len(data))
-
-	// 开始事务
-	tx, err := si.db.BeginWithRetry()
+		reader = r
+		return decompressResult(file.Key, r)
+	}, func(h *ResultFileHeader) resultSlice {
+		return resultSlice{TableName: tableName, RtpLevel: s3SliceRtpLevel(h.RtpLevel, file.Mode), SrNumber: h.SrNumber}
+	})
 	if err != nil {
-		return fmt.Errorf("开始事务失败: %v", err)
-	}
-	defer tx.Rollback()
-
-	// 准备批量插入数据
-	values := make([]string, 0, len(data))
-	args := make([]interface{}, 0, len(data)*6)
-	argIndex := 1
-
-	for _, item := range data {
-		// 将gd字段转换为JSON字符串以适配JSONB类型
-		var detailVal interface{}
-		if item["gd"] != nil {
-			// 将gd字段转换为JSON字符串
-			gdJSON, err := json.Marshal(item["gd"])
-			if err != nil {
-				return fmt.Errorf("序列化gd字段失败: %v", err)
-			}
-			detailVal = string(gdJSON)
-		}
-
-		// 精度修正：将win字段四舍五入到2位小数
-		var winValue float64
-		if aw, ok := item["aw"].(float64); ok {
-			// 四舍五入到2位小数，避免浮点数精度问题
-			winValue = math.Round(aw*100) / 100
-		} else {
-			winValue = 0.0
-		}
-
-		var totalBet float64
-		if tb, ok := item["tb"].(float64); ok {
-			// 四舍五入到2位小数，避免浮点数精度问题
-			totalBet = math.Round(tb*100) / 100
-		} else {
-			totalBet = 0.0
-		}
-
-		// 根据模式处理rtpLevel：fb模式需要+0.1
-		rtpLevelVal := float64(rtpLevel)
-		if mode == "fb" {
-			rtpLevelVal = float64(rtpLevel) + 0.1
+		// 解析或写入时才暴露的下载错误，按读取器记录的最终错误分类
+		if reader != nil && reader.err != nil {
+			return &s3FileError{Class: reader.err.Class, Err: err}
 		}
-
-		*globalSrId++ // 递增全局srId
-
-		// 构建VALUES子句
-		values = append(values, fmt.Sprintf("($%d, $%d, $%d, $%d, $%d, $%d)",
-			argIndex, argIndex+1, argIndex+2, argIndex+3, argIndex+4, argIndex+5))
-
-		// 添加参数
-		args = append(args, rtpLevelVal, testNum, *globalSrId, totalBet, winValue, detailVal)
-		argIndex += 6
-	}
-
-	// 构建批量插入SQL
-	query := fmt.Sprintf(`
-		INSERT INTO "%s" ("rtpLevel", "srNumber", "srId", "bet", "win", "detail")
-		VALUES %s
-	`, tableName, strings.Join(values, ", "))
-
-	// 执行批量插入
-	_, err = tx.Exec(query, args...)
-	if err != nil {
-		return fmt.Errorf("批量插入失败: %v", err)
-	}
-
-	// 提交事务
-	if err := tx.Commit(); err != nil {
-		return fmt.Errorf("提交事务失败: %v", err)
-	}
-
-	fmt.Printf("    ✅ 第 %d 批数据处理完成\n", batchNum)
-	return nil
-}
-
-// generatePlaceholders 生成占位符字符串
-func (si *S3Importer) generatePlaceholders(count int) string {
-	if count <= 0 {
-		return ""
-	}
-
-	// 生成 (?, ?, ?, ?, ?, ?) 格式的占位符
-	placeholder := "($1, $2, $3, $4, $5, $6)"
-	result := placeholder
-
-	for i := 1; i < count; i++ {
-		offset := i * 6
-		result += fmt.Sprintf(", ($%d, $%d, $%d, $%d, $%d, $%d)",
-			offset+1, offset+2, offset+3, offset+4, offset+5, offset+6)
-	}
-
-	return result
-}
-
-// importS3FilesConcurrentStream 并发流式导入S3文件 - 优化版本
-func (si *S3Importer) importS3FilesConcurrentStream(files []S3FileInfo, tableName string) error {
-	// 动态调整并发数量 - 优化策略
-	maxConcurrency := si.calculateOptimalConcurrency(len(files))
-
-	// 创建信号量控制并发
-	semaphore := make(chan struct{}, maxConcurrency)
-	var wg sync.WaitGroup
-	var mu sync.Mutex
-	var errors []error
-	var successCount int
-	var totalProcessed int64
-	var totalBytes int64
-	startTime := time.Now()
-
-	fmt.Printf("🚀 开始并发流式处理 %d 个文件，最大并发数: %d\n", len(files), maxConcurrency)
-
-	for i, file := range files {
-		wg.Add(1)
-		go func(index int, f S3FileInfo) {
-			defer wg.Done()
-
-			// 获取信号量
-			semaphore <- struct{}{}
-			defer func() { <-semaphore }()
-
-			fmt.Printf("🔄 [%d/%d] 开始处理文件: %s (大小: %.2fMB)\n",
-				index+1, len(files), f.Key, float64(f.Size)/(1024*1024))
-			startTime := time.Now()
-
-			// 流式处理单个文件
-			if err := si.importS3FileStream(f, tableName); err != nil {
-				mu.Lock()
-				errors = append(errors, fmt.Errorf("文件 %s 处理失败: %v", f.Key, err))
-				mu.Unlock()
-				fmt.Printf("❌ [%d/%d] 文件处理失败: %s - %v\n", index+1, len(files), f.Key, err)
-			} else {
-				mu.Lock()
-				successCount++
-				totalProcessed++
-				totalBytes += f.Size
-				mu.Unlock()
-				duration := time.Since(startTime)
-				rate := float64(f.Size) / (1024 * 1024) / duration.Seconds()
-				fmt.Printf("✅ [%d/%d] 文件处理完成: %s (耗时: %v, 速度: %.2fMB/s)\n",
-					index+1, len(files), f.Key, duration, rate)
-
-				// 定期检查连接健康状态和续期
-				if successCount%10 == 0 {
-					if err := si.db.CheckConnectionHealth(); err != nil {
-						fmt.Printf("⚠️ 连接健康检查失败: %v\n", err)
-					}
-				}
-
-				// 每处理50个文件延长一次连接生存时间
-				if successCount%50 == 0 {
-					if err := si.db.ExtendConnection(); err != nil {
-						fmt.Printf("⚠️ 连接续期失败: %v\n", err)
-					}
-				}
-			}
-		}(i, file)
-	}
-
-	wg.Wait()
-
-	// 输出处理结果
-	totalDuration := time.Since(startTime)
-	avgSpeed := float64(totalBytes) / (1024 * 1024) / totalDuration.Seconds()
-
-	fmt.Printf("\n📊 处理结果统计:\n")
-	fmt.Printf("  - 总文件数: %d\n", len(files))
-	fmt.Printf("  - 成功处理: %d\n", successCount)
-	fmt.Printf("  - 处理失败: %d\n", len(errors))
-	fmt.Printf("  - 总耗时: %v\n", totalDuration)
-	fmt.Printf("  - 总数据量: %.2fMB\n", float64(totalBytes)/(1024*1024))
-	fmt.Printf("  - 平均速度: %.2fMB/s\n", avgSpeed)
-	fmt.Printf("  - 平均每文件: %v\n", totalDuration/time.Duration(len(files)))
-
-	// 长时间导入警告
-	if totalDuration > 30*time.Minute {
-		fmt.Printf("⚠️ 导入时间超过30分钟，建议检查连接配置\n")
+		return err
 	}
-	if totalDuration > 60*time.Minute {
-		fmt.Printf("⚠️ 导入时间超过1小时，建议优化导入策略\n")
+	if reader != nil && reader.resumes > 0 {
+		fmt.Printf("  🔁 下载中断 %d 次，均已从断点续传\n", reader.resumes)
 	}
-
-	if len(errors) > 0 {
-		fmt.Printf("\n❌ 错误详情:\n")
-		for i, err := range errors {
-			fmt.Printf("  %d. %v\n", i+1, err)
-		}
-		return fmt.Errorf("处理过程中出现 %d 个错误", len(errors))
+	if !skipped && !si.options.DryRun {
+		fmt.Printf("  ✅ 总共写入 %d 条记录\n", count)
 	}
-
-	fmt.Printf("🎉 所有文件处理完成！\n")
 	return nil
 }
 
-// calculateOptimalConcurrency 计算最优并发数
-func (si *S3Importer) calculateOptimalConcurrency(fileCount int) int {
-	// 基础并发数
-	baseConcurrency := 5
-
-	// 根据文件数量动态调整
-	if fileCount <= 10 {
-		return min(baseConcurrency, fileCount)
-	} else if fileCount <= 50 {
-		return min(8, fileCount)
-	} else if fileCount <= 100 {
-		return min(12, fileCount)
-	} else {
-		return min(16, fileCount) // 最多16个并发
-	}
-}
-
-// min 返回两个整数中的较小值
-func min(a, b int) int {
-	if a < b {
-		return a
+// printImportFailureClasses 按错误分类输出失败文件数
+func printImportFailureClasses(classes map[string]int) {
+	if len(classes) == 0 {
+		return
 	}
-	return b
-}
-
-// max 返回两个整数中的较大值
-func max(a, b int) int {
-	if a > b {
-		return a
+	names := make([]string, 0, len(classes))
+	for name := range classes {
+		names = append(names, name)
 	}
-	return b
-}
-
-// calculateOptimalBatchSize 计算最优批处理大小
-func (si *S3Importer) calculateOptimalBatchSize(fileSize int64) int {
-	// 根据文件大小动态调整，平衡内存使用和性能
-	if fileSize < 10*1024*1024 { // < 10MB
-		return 5000
-	} else if fileSize < 30*1024*1024 { // < 30MB
-		return 2000
-	} else if fileSize < 50*1024*1024 { // < 50MB
-		return 1000
-	} else if fileSize < 100*1024*1024 { // < 100MB
-		return 500
-	} else { // >= 100MB
-		return 200 // 超大文件使用更小批次
+	sort.Strings(names)
+	for _, name := range names {
+		fmt.Printf("    · %s: %d\n", name, classes[name])
 	}
 }
 
-// createS3TargetTable 创建S3导入的目标数据表
-func (si *S3Importer) createS3TargetTable(tableName string) error {
-	query := fmt.Sprintf(`
-		CREATE TABLE IF NOT EXISTS "%s" (
-			"id" SERIAL PRIMARY KEY,
-			"rtpLevel" REAL NOT NULL,
-			"srNumber" INTEGER NOT NULL,
-			"srId" SERIAL NOT NULL,
-			"bet" NUMERIC NOT NULL,
-			"win" NUMERIC NOT NULL,
-			"detail" JSONB,
-			"created_at" TIMESTAMP DEFAULT CURRENT_TIMESTAMP
-		);
-	`, tableName)
-
-	// 执行创建表语句
-	_, err := si.db.DB.Exec(query)
-	if err != nil {
-		return fmt.Errorf("创建表失败: %v", err)
-	}
-
-	// 创建索引
-	indexQueries := []string{
-		fmt.Sprintf(`CREATE INDEX IF NOT EXISTS "%s_rtpLevel_idx" ON "%s" ("rtpLevel")`, tableName, tableName),
-		fmt.Sprintf(`CREATE INDEX IF NOT EXISTS "%s_srNumber_idx" ON "%s" ("srNumber")`, tableName, tableName),
-		fmt.Sprintf(`CREATE INDEX IF NOT EXISTS "%s_srId_idx" ON "%s" ("srId")`, tableName, tableName),
-		fmt.Sprintf(`CREATE INDEX IF NOT EXISTS "%s_rtpLevel_srNumber_idx" ON "%s" ("rtpLevel", "srNumber")`, tableName, tableName),
-		fmt.Sprintf(`CREATE INDEX IF NOT EXISTS "%s_rtpLevel_srNumber_srId_idx" ON "%s" ("rtpLevel", "srNumber", "srId")`, tableName, tableName),
-		fmt.Sprintf(`CREATE INDEX IF NOT EXISTS "%s_detail_gin_idx" ON "%s" USING GIN ("detail")`, tableName, tableName),
-	}
-
-	for _, indexSQL := range indexQueries {
-		_, err := si.db.DB.Exec(indexSQL)
-		if err != nil {
-			return fmt.Errorf("创建索引失败: %v", err)
-		}
+// s3SliceRtpLevel 目标表中的 rtpLevel：fb模式需要+0.1
+func s3SliceRtpLevel(rtpLevel int, mode string) float64 {
+	if mode == "fb" {
+		return float64(rtpLevel) + 0.1
 	}
-
-	fmt.Printf("✅ 成功创建S3目标表: %s", tableName)
-	return nil
+	return float64(rtpLevel)
 }
 
 // importS3FilesSequentialStream 串行流式导入S3文件 - 避免同一游戏文件的数据库锁冲突
 func (si *S3Importer) importS3FilesSequentialStream(files []S3FileInfo, tableName string) error {
 	var errors []error
+	failureClasses := make(map[string]int)
 	var successCount int
 	var totalProcessed int64
 	var totalBytes int64
@@ -1213,15 +540,27 @@ func (si *S3Importer) importS3FilesSequentialStream(files []S3FileInfo, tableNam
 
 	fmt.Printf("🚀 开始串行流式处理 %d 个文件（避免数据库锁冲突）\n", len(files))
 
+	session := newImportSession(si.db, si.config, si.options)
+	if err := session.Begin(); err != nil {
+		return err
+	}
+	defer session.Rollback()
+
 	for i, file := range files {
 		fmt.Printf("🔄 [游戏%d-%s: %d/%d] 开始处理文件: %s (大小: %.2fMB)\n",
 			file.GameID, file.Mode, i+1, len(files), file.Key, float64(file.Size)/(1024*1024))
 		fileStartTime := time.Now()
 
 		// 流式处理单个文件
-		if err := si.importS3FileStream(file, tableName); err != nil {
-			errors = append(errors, fmt.Errorf("文件 %s 处理失败: %v", file.Key, err))
-			fmt.Printf("❌ [游戏%d-%s: %d/%d] 文件处理失败: %s - %v\n", file.GameID, file.Mode, i+1, len(files), file.Key, err)
+		if err := si.importS3FileStream(session, file, tableName); err != nil {
+			class := importFailureClass(err)
+			errors = append(errors, fmt.Errorf("文件 %s 处理失败 [%s]: %v", file.Key, class, err))
+			failureClasses[class]++
+			fmt.Printf("❌ [游戏%d-%s: %d/%d] 文件处理失败 [%s]: %s - %v\n", file.GameID, file.Mode, i+1, len(files), class, file.Key, err)
+			// 整个游戏共用一个事务时，后续文件会随回滚一并丢弃，无需继续
+			if si.options.Scope == ImportScopeGame {
+				break
+			}
 		} else {
 			successCount++
 			totalProcessed++
@@ -1238,13 +577,14 @@ func (si *S3Importer) importS3FilesSequentialStream(files []S3FileInfo, tableNam
 	fmt.Printf("  - 总文件数: %d\n", len(files))
 	fmt.Printf("  - 成功处理: %d\n", successCount)
 	fmt.Printf("  - 失败文件: %d\n", len(errors))
+	printImportFailureClasses(failureClasses)
 	fmt.Printf("  - 总数据量: %.2f MB\n", float64(totalBytes)/(1024*1024))
 	fmt.Printf("  - 总耗时: %v\n", totalDuration)
 	if len(files) > 0 {
 		fmt.Printf("  - 平均速度: %.2f MB/s\n", float64(totalBytes)/(1024*1024)/totalDuration.Seconds())
 	}
 
-	// 如果有错误，返回汇总错误信息
+	// 如果有错误，返回汇总错误信息（共享事务随 defer 回滚）
 	if len(errors) > 0 {
 		fmt.Printf("⚠️  部分文件处理失败:\n")
 		for i, err := range errors {
@@ -1253,5 +593,5 @@ func (si *S3Importer) importS3FilesSequentialStream(files []S3FileInfo, tableNam
 		return fmt.Errorf("处理过程中出现 %d 个错误，详细信息见上方输出", len(errors))
 	}
 
-	return nil
+	return session.Commit()
 }
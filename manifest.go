@@ -0,0 +1,198 @@
+package main
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+// manifestFileName 生成目录中的生成清单文件名
+const manifestFileName = "manifest.json"
+
+// GenerationManifest 生成清单：记录目录中每个结果文件由哪次生成运行产生及其内容摘要
+// 多次运行（如先 generate 再 generate3，或部分文件重新生成）写入同一目录时合并，文件条目按文件名替换
+type GenerationManifest struct {
+	GameID    int            `json:"gameId"`
+	UpdatedAt string         `json:"updatedAt"`
+	Runs      []ManifestRun  `json:"runs"`
+	Files     []ManifestFile `json:"files"`
+}
+
+// ManifestRun 一次生成运行（一个游戏、一个策略）的来源信息
+type ManifestRun struct {
+	ID           string           `json:"id"`
+	Strategy     string           `json:"strategy"`
+	BaseSeed     int64            `json:"baseSeed"`
+	StartedAt    string           `json:"startedAt"`
+	FinishedAt   string           `json:"finishedAt"`
+	ConfigSHA256 string           `json:"configSha256"` // 生效配置快照的哈希，见 configSnapshotSHA256
+	SourceTable  string           `json:"sourceTable"`
+	SourceRows   int64            `json:"sourceRows"` // 源表总行数
+	PoolRows     ManifestPoolRows `json:"poolRows"`   // 加载到候选数据池的行数
+}
+
+// ManifestPoolRows 候选数据池各部分的行数
+type ManifestPoolRows struct {
+	Win    int `json:"win"`
+	Profit int `json:"profit"`
+	NoWin  int `json:"noWin"`
+}
+
+// ManifestFile 单个结果文件的条目
+type ManifestFile struct {
+	Name     string      `json:"name"`
+	Run      string      `json:"run"` // 所属运行的 ID
+	RtpLevel float64     `json:"rtpLevel"`
+	SrNumber int         `json:"srNumber"`
+	Strategy string      `json:"strategy"`
+	Seed     int64       `json:"seed"`
+	Rows     int         `json:"rows"`
+	TotalBet Money       `json:"totalBet"`
+	TotalWin Money       `json:"totalWin"`
+	RTP      float64     `json:"rtp"`
+	GWT      map[int]int `json:"gwt"`    // 各 gwt 取值的条数
+	SHA256   string      `json:"sha256"` // sha256:<hex>，与导入清单中本地文件的校验和格式一致
+}
+
+// newManifestRun 生成运行的来源信息，ID 由开始时间与策略名组成
+func newManifestRun(config *Config, strategy string, baseSeed int64, start time.Time) ManifestRun {
+	return ManifestRun{
+		ID:           fmt.Sprintf("%s-%s", start.UTC().Format("20060102T150405Z"), strategy),
+		Strategy:     strategy,
+		BaseSeed:     baseSeed,
+		StartedAt:    start.Format(time.RFC3339),
+		ConfigSHA256: configSnapshotSHA256(config),
+	}
+}
+
+// configSnapshotSHA256 生效配置的快照哈希
+// 数据库环境与 S3 配置不影响生成结果且包含凭据，不计入快照
+func configSnapshotSHA256(config *Config) string {
+	var zero Config
+	snapshot := *config
+	snapshot.Environments = nil
+	snapshot.DefaultEnv = ""
+	snapshot.Database = zero.Database
+	snapshot.S3 = zero.S3
+	data, err := json.Marshal(snapshot)
+	if err != nil {
+		return ""
+	}
+	sum := sha256.Sum256(data)
+	return "sha256:" + hex.EncodeToString(sum[:])
+}
+
+// readGenerationManifest 读取目录中的生成清单，文件不存在时返回 nil
+func readGenerationManifest(dir string) (*GenerationManifest, error) {
+	data, err := os.ReadFile(filepath.Join(dir, manifestFileName))
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("读取生成清单失败: %v", err)
+	}
+	var manifest GenerationManifest
+	if err := json.Unmarshal(data, &manifest); err != nil {
+		return nil, fmt.Errorf("解析生成清单失败: %v", err)
+	}
+	return &manifest, nil
+}
+
+// updateGenerationManifest 将一次运行生成的文件合并写入目录的生成清单
+// 同名文件的旧条目被替换，磁盘上已不存在的文件和不再被引用的运行被移除；清单先写临时文件再原子重命名
+func updateGenerationManifest(dir string, gameID int, run ManifestRun, files []ManifestFile) error {
+	manifest, err := readGenerationManifest(dir)
+	if err != nil {
+		fmt.Printf("⚠️ %v，将重新生成清单\n", err)
+		manifest = nil
+	}
+	if manifest == nil {
+		manifest = &GenerationManifest{GameID: gameID}
+	}
+
+	written := make(map[string]bool, len(files))
+	for i := range files {
+		files[i].Run = run.ID
+		written[files[i].Name] = true
+	}
+	kept := files
+	for _, f := range manifest.Files {
+		if written[f.Name] {
+			continue
+		}
+		if _, err := os.Stat(filepath.Join(dir, f.Name)); err != nil {
+			continue
+		}
+		kept = append(kept, f)
+	}
+	sort.Slice(kept, func(i, j int) bool {
+		if kept[i].RtpLevel != kept[j].RtpLevel {
+			return kept[i].RtpLevel < kept[j].RtpLevel
+		}
+		if kept[i].SrNumber != kept[j].SrNumber {
+			return kept[i].SrNumber < kept[j].SrNumber
+		}
+		return kept[i].Name < kept[j].Name
+	})
+
+	referenced := make(map[string]bool)
+	for _, f := range kept {
+		referenced[f.Run] = true
+	}
+	var runs []ManifestRun
+	for _, r := range manifest.Runs {
+		if referenced[r.ID] && r.ID != run.ID {
+			runs = append(runs, r)
+		}
+	}
+	runs = append(runs, run)
+
+	manifest.GameID = gameID
+	manifest.UpdatedAt = time.Now().Format(time.RFC3339)
+	manifest.Runs = runs
+	manifest.Files = kept
+	data, err := json.MarshalIndent(manifest, "", "  ")
+	if err != nil {
+		return fmt.Errorf("序列化生成清单失败: %v", err)
+	}
+	return writeFileAtomic(filepath.Join(dir, manifestFileName), append(data, '\n'))
+}
+
+// writeFileAtomic 写入同目录下的临时文件，fsync 后重命名为目标文件
+func writeFileAtomic(path string, data []byte) error {
+	dir, name := filepath.Split(path)
+	tmp, err := os.CreateTemp(dir, "."+strings.TrimSuffix(name, filepath.Ext(name))+".partial-*")
+	if err != nil {
+		return fmt.Errorf("创建临时文件失败: %v", err)
+	}
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		os.Remove(tmp.Name())
+		return fmt.Errorf("写入临时文件失败: %v", err)
+	}
+	if err := tmp.Chmod(0644); err != nil {
+		tmp.Close()
+		os.Remove(tmp.Name())
+		return fmt.Errorf("设置文件权限失败: %v", err)
+	}
+	if err := tmp.Sync(); err != nil {
+		tmp.Close()
+		os.Remove(tmp.Name())
+		return fmt.Errorf("fsync失败: %v", err)
+	}
+	if err := tmp.Close(); err != nil {
+		os.Remove(tmp.Name())
+		return fmt.Errorf("关闭临时文件失败: %v", err)
+	}
+	if err := os.Rename(tmp.Name(), path); err != nil {
+		os.Remove(tmp.Name())
+		return fmt.Errorf("重命名临时文件失败: %v", err)
+	}
+	return nil
+}
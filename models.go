@@ -9,8 +9,8 @@ import (
 // GameResultData 游戏结果数据结构
 type GameResultData struct {
 	ID        int       `json:"id" db:"id"`
-	TB        int       `json:"tb" db:"tb"`   // 投注额
-	AW        float64   `json:"aw" db:"aw"`   // 盈利额
+	TB        Money     `json:"tb" db:"tb"`   // 投注额
+	AW        Money     `json:"aw" db:"aw"`   // 盈利额
 	GWT       int       `json:"gwt" db:"gwt"` // 奖励类型 (2=大奖, 3=巨奖, 4=超巨奖)
 	SP        bool      `json:"sp" db:"sp"`   // 是否特殊玩法
 	FB        int       `json:"fb" db:"fb"`   // 是否为购买
@@ -19,10 +19,36 @@ type GameResultData struct {
 	UpdatedAt time.Time `json:"updatedAt" db:"updatedAt"`
 }
 
+// ResultRow 输出文件 data 数组中的一行，字段顺序与 JSON 键名排序一致（aw, fb, gd, gwt, sp, tb）
+type ResultRow struct {
+	AW  Money           `json:"aw"`  // 盈利额
+	FB  int             `json:"fb"`  // 是否为购买
+	GD  json.RawMessage `json:"gd"`  // 原数据
+	GWT int             `json:"gwt"` // 奖励类型
+	SP  bool            `json:"sp"`  // 是否特殊玩法
+	TB  Money           `json:"tb"`  // 投注额
+}
+
+// NewResultRow 由源数据构造输出行
+func NewResultRow(item GameResultData) (ResultRow, error) {
+	gd, err := json.Marshal(item.GD.Data)
+	if err != nil {
+		return ResultRow{}, err
+	}
+	return ResultRow{AW: item.AW, FB: item.FB, GD: gd, GWT: item.GWT, SP: item.SP, TB: item.TB}, nil
+}
+
+// Detail 写入 detail(JSONB) 列的值，gd 缺失或为 null 时写入 NULL
+func (r ResultRow) Detail() interface{} {
+	if len(r.GD) == 0 || string(r.GD) == "null" {
+		return nil
+	}
+	return string(r.GD)
+}
 
 type GameResult struct {
 	RtpLevel float64 //rtp等级
-	SrNumber int // 第几次
+	SrNumber int     // 第几次
 	SrId     int
 	Bet      float64
 	Win      float64
@@ -48,12 +74,12 @@ func (j *JsonData) Scan(value interface{}) error {
 		j.Data = nil
 		return nil
 	}
-	
+
 	bytes, ok := value.([]byte)
 	if !ok {
 		return nil
 	}
-	
+
 	return json.Unmarshal(bytes, &j.Data)
 }
 
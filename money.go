@@ -0,0 +1,221 @@
+package main
+
+import (
+	"database/sql/driver"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+)
+
+// Money 金额，以最小货币单位（分）存储的整数
+// 生成、输出文件与导入全程使用同一表示，保证校验的 RTP 与写入 bet/win NUMERIC 列的 RTP 完全一致
+type Money int64
+
+// MoneyFromFloat 浮点金额四舍五入到分
+func MoneyFromFloat(v float64) Money {
+	return Money(math.Round(v * 100))
+}
+
+// ParseMoney 按十进制字面量精确解析金额（含科学计数法），超过两位的小数四舍五入到分，超出 int64 分的范围时报错
+func ParseMoney(s string) (Money, error) {
+	s = strings.TrimSpace(s)
+	if s == "" {
+		return 0, fmt.Errorf("金额为空")
+	}
+	// 科学计数法先移动小数点展开为普通十进制写法，同样精确解析
+	if i := strings.IndexAny(s, "eE"); i >= 0 {
+		expanded, err := expandExponent(s[:i], s[i+1:])
+		if err != nil {
+			return 0, fmt.Errorf("无效金额 %q: %v", s, err)
+		}
+		s = expanded
+	}
+
+	neg := false
+	switch s[0] {
+	case '-':
+		neg = true
+		s = s[1:]
+	case '+':
+		s = s[1:]
+	}
+	intPart, fracPart := s, ""
+	if i := strings.IndexByte(s, '.'); i >= 0 {
+		intPart, fracPart = s[:i], s[i+1:]
+	}
+	if intPart == "" && fracPart == "" {
+		return 0, fmt.Errorf("无效金额 %q", s)
+	}
+	for _, part := range []string{intPart, fracPart} {
+		for _, c := range part {
+			if c < '0' || c > '9' {
+				return 0, fmt.Errorf("无效金额 %q", s)
+			}
+		}
+	}
+
+	var units int64
+	if intPart != "" {
+		v, err := strconv.ParseInt(intPart, 10, 64)
+		if err != nil {
+			return 0, fmt.Errorf("无效金额 %q: %v", s, err)
+		}
+		if v > math.MaxInt64/100 {
+			return 0, fmt.Errorf("无效金额 %q: 超出范围", s)
+		}
+		units = v * 100
+	}
+	var cents int64
+	for i := 0; i < 2; i++ {
+		digit := int64(0)
+		if i < len(fracPart) {
+			digit = int64(fracPart[i] - '0')
+		}
+		if i == 0 {
+			cents += digit * 10
+		} else {
+			cents += digit
+		}
+	}
+	if len(fracPart) > 2 && fracPart[2] >= '5' {
+		cents++
+	}
+	if units > math.MaxInt64-cents {
+		return 0, fmt.Errorf("无效金额 %q: 超出范围", s)
+	}
+	units += cents
+	if neg {
+		units = -units
+	}
+	return Money(units), nil
+}
+
+// maxMoneyExponent 科学计数法允许的指数绝对值，超出时必然溢出或舍入为 0
+const maxMoneyExponent = 100
+
+// expandExponent 将 mantissa × 10^exp 展开为不带指数的十进制字面量，例如 ("1.005", "2") → "100.5"
+func expandExponent(mantissa, exp string) (string, error) {
+	e, err := strconv.Atoi(exp)
+	if err != nil {
+		return "", fmt.Errorf("无效指数 %q", exp)
+	}
+	if e > maxMoneyExponent || e < -maxMoneyExponent {
+		return "", fmt.Errorf("指数 %d 超出范围", e)
+	}
+	sign := ""
+	if mantissa != "" && (mantissa[0] == '-' || mantissa[0] == '+') {
+		sign, mantissa = mantissa[:1], mantissa[1:]
+	}
+	intPart, fracPart := mantissa, ""
+	if i := strings.IndexByte(mantissa, '.'); i >= 0 {
+		intPart, fracPart = mantissa[:i], mantissa[i+1:]
+	}
+	if intPart == "" && fracPart == "" {
+		return "", fmt.Errorf("缺少有效数字")
+	}
+	digits := intPart + fracPart
+	point := len(intPart) + e
+	switch {
+	case point <= 0:
+		return sign + "0." + strings.Repeat("0", -point) + digits, nil
+	case point >= len(digits):
+		return sign + digits + strings.Repeat("0", point-len(digits)), nil
+	default:
+		return sign + digits[:point] + "." + digits[point:], nil
+	}
+}
+
+// Float64 转为浮点金额，仅用于比例计算与兼容旧接口
+func (m Money) Float64() float64 {
+	return float64(m) / 100
+}
+
+// String 固定两位小数
+func (m Money) String() string {
+	sign := ""
+	v := int64(m)
+	if v < 0 {
+		sign = "-"
+		v = -v
+	}
+	return fmt.Sprintf("%s%d.%02d", sign, v/100, v%100)
+}
+
+// Format 实现 fmt.Formatter：%d 输出分，其余动词按元单位浮点格式化，例如 %.2f
+func (m Money) Format(f fmt.State, verb rune) {
+	switch verb {
+	case 'd':
+		fmt.Fprintf(f, fmt.FormatString(f, verb), int64(m))
+	case 's', 'v':
+		fmt.Fprintf(f, fmt.FormatString(f, 's'), m.String())
+	default:
+		fmt.Fprintf(f, fmt.FormatString(f, verb), m.Float64())
+	}
+}
+
+// MulRatio 金额乘以比例并四舍五入到分，用于目标金额与容差计算
+func (m Money) MulRatio(r float64) Money {
+	return Money(math.Round(float64(m) * r))
+}
+
+// Ratio 两个金额的比值，例如 总中奖/总投注 = RTP
+func (m Money) Ratio(d Money) float64 {
+	if d == 0 {
+		return 0
+	}
+	return float64(m) / float64(d)
+}
+
+// MarshalJSON 输出最短十进制表示，与旧版 float64 序列化结果逐字节一致
+func (m Money) MarshalJSON() ([]byte, error) {
+	return strconv.AppendFloat(nil, m.Float64(), 'f', -1, 64), nil
+}
+
+// UnmarshalJSON 从 JSON 数字字面量精确解析
+func (m *Money) UnmarshalJSON(data []byte) error {
+	s := string(data)
+	if s == "null" {
+		*m = 0
+		return nil
+	}
+	v, err := ParseMoney(strings.Trim(s, `"`))
+	if err != nil {
+		return err
+	}
+	*m = v
+	return nil
+}
+
+// Value 实现 driver.Valuer 接口，以两位小数的十进制字符串写入 NUMERIC 列
+func (m Money) Value() (driver.Value, error) {
+	return m.String(), nil
+}
+
+// Scan 实现 sql.Scanner 接口
+// NUMERIC 列以十进制字符串精确解析；int64 来自整数列，按元解释；float64 四舍五入到分
+func (m *Money) Scan(value interface{}) error {
+	switch v := value.(type) {
+	case nil:
+		*m = 0
+	case int64:
+		*m = Money(v * 100)
+	case float64:
+		*m = MoneyFromFloat(v)
+	case []byte:
+		parsed, err := ParseMoney(string(v))
+		if err != nil {
+			return err
+		}
+		*m = parsed
+	case string:
+		parsed, err := ParseMoney(v)
+		if err != nil {
+			return err
+		}
+		*m = parsed
+	default:
+		return fmt.Errorf("无法将 %T 转换为金额", value)
+	}
+	return nil
+}
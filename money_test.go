@@ -0,0 +1,198 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"math/rand"
+	"testing"
+)
+
+func TestParseMoney(t *testing.T) {
+	tests := []struct {
+		in      string
+		want    Money
+		wantErr bool
+	}{
+		// 常规写法
+		{in: "0", want: 0},
+		{in: "1", want: 100},
+		{in: "1.5", want: 150},
+		{in: "1.50", want: 150},
+		{in: ".5", want: 50},
+		{in: "5.", want: 500},
+		{in: "+2.25", want: 225},
+		{in: "-2.25", want: -225},
+		{in: " 3.07 ", want: 307},
+		{in: "92233720368547758.07", want: 9223372036854775807},
+		// 半分四舍五入，负数按绝对值进位
+		{in: "0.005", want: 1},
+		{in: "0.004", want: 0},
+		{in: "1.235", want: 124},
+		{in: "1.245", want: 125},
+		{in: "-0.005", want: -1},
+		{in: "-0.004", want: 0},
+		{in: "-1.235", want: -124},
+		{in: "-1.234", want: -123},
+		// 超过两位小数只看第三位
+		{in: "1.23456", want: 123},
+		{in: "1.2351", want: 124},
+		{in: "0.0049999", want: 0},
+		{in: "0.00500", want: 1},
+		{in: "9.995", want: 1000},
+		{in: "-9.995", want: -1000},
+		// 科学计数法同样精确解析
+		{in: "1e2", want: 10000},
+		{in: "1E2", want: 10000},
+		{in: "1.5e-1", want: 15},
+		{in: "-2.5e0", want: -250},
+		{in: "1.005e0", want: 101},
+		{in: "5e-3", want: 1},
+		{in: "-5e-3", want: -1},
+		{in: "4.9e-3", want: 0},
+		{in: "12345e-4", want: 123},
+		{in: "1.2e+3", want: 120000},
+		{in: "1e-100", want: 0},
+		// 无效输入
+		{in: "", wantErr: true},
+		{in: "-", wantErr: true},
+		{in: ".", wantErr: true},
+		{in: "abc", wantErr: true},
+		{in: "1.2.3", wantErr: true},
+		{in: "1,5", wantErr: true},
+		{in: "--1", wantErr: true},
+		{in: "e5", wantErr: true},
+		{in: "1e", wantErr: true},
+		{in: "1e1.5", wantErr: true},
+		{in: "1e101", wantErr: true},
+		{in: "1e20", wantErr: true},
+		{in: "92233720368547758.08", wantErr: true},
+		{in: "92233720368547758.075", wantErr: true},
+		{in: "NaN", wantErr: true},
+		{in: "Inf", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.in, func(t *testing.T) {
+			got, err := ParseMoney(tt.in)
+			if tt.wantErr {
+				if err == nil {
+					t.Fatalf("ParseMoney(%q) = %d，期望错误", tt.in, int64(got))
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("ParseMoney(%q) 意外错误: %v", tt.in, err)
+			}
+			if got != tt.want {
+				t.Fatalf("ParseMoney(%q) = %d 分，期望 %d 分", tt.in, int64(got), int64(tt.want))
+			}
+		})
+	}
+}
+
+func TestMoneyScan(t *testing.T) {
+	tests := []struct {
+		name    string
+		value   interface{}
+		want    Money
+		wantErr bool
+	}{
+		{name: "nil", value: nil, want: 0},
+		{name: "[]byte NUMERIC", value: []byte("12.34"), want: 1234},
+		{name: "[]byte 半分进位", value: []byte("12.345"), want: 1235},
+		{name: "[]byte 负数", value: []byte("-0.50"), want: -50},
+		{name: "[]byte 无效", value: []byte("abc"), wantErr: true},
+		{name: "string", value: "7.5", want: 750},
+		{name: "string 负数半分", value: "-0.015", want: -2},
+		{name: "string 无效", value: "", wantErr: true},
+		{name: "float64", value: float64(1.25), want: 125},
+		{name: "float64 负数", value: float64(-3.5), want: -350},
+		{name: "int64 按元解释", value: int64(5), want: 500},
+		{name: "int64 负数按元解释", value: int64(-12), want: -1200},
+		{name: "int64 零", value: int64(0), want: 0},
+		{name: "不支持的类型", value: true, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			m := Money(-999)
+			err := m.Scan(tt.value)
+			if tt.wantErr {
+				if err == nil {
+					t.Fatalf("Scan(%#v) = %d，期望错误", tt.value, int64(m))
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Scan(%#v) 意外错误: %v", tt.value, err)
+			}
+			if m != tt.want {
+				t.Fatalf("Scan(%#v) = %d 分，期望 %d 分", tt.value, int64(m), int64(tt.want))
+			}
+		})
+	}
+}
+
+func TestMoneyFormat(t *testing.T) {
+	tests := []struct {
+		m      Money
+		format string
+		want   string
+	}{
+		{12345, "%v", "123.45"},
+		{12345, "%s", "123.45"},
+		{-5, "%v", "-0.05"},
+		{-12345, "%d", "-12345"},
+		{12345, "%.2f", "123.45"},
+		{5, "%8.2f", "    0.05"},
+		{100, "%.1f", "1.0"},
+	}
+	for _, tt := range tests {
+		if got := fmt.Sprintf(tt.format, tt.m); got != tt.want {
+			t.Fatalf("Sprintf(%q, %d) = %q，期望 %q", tt.format, int64(tt.m), got, tt.want)
+		}
+	}
+}
+
+func TestMoneyRoundTrip(t *testing.T) {
+	values := []Money{0, 1, -1, 5, -5, 10, 99, 100, 101, -101, 12345, -12345, 1000000, 123456789012}
+	rng := rand.New(rand.NewSource(1))
+	for i := 0; i < 1000; i++ {
+		values = append(values, Money(rng.Int63n(2_000_000_000_000)-1_000_000_000_000))
+	}
+	for _, m := range values {
+		data, err := json.Marshal(m)
+		if err != nil {
+			t.Fatalf("MarshalJSON(%d) 失败: %v", int64(m), err)
+		}
+		var back Money
+		if err := json.Unmarshal(data, &back); err != nil || back != m {
+			t.Fatalf("MarshalJSON(%d) = %s，解析回 %d (%v)", int64(m), data, int64(back), err)
+		}
+		for _, format := range []string{"%v", "%.2f"} {
+			s := fmt.Sprintf(format, m)
+			parsed, err := ParseMoney(s)
+			if err != nil || parsed != m {
+				t.Fatalf("Sprintf(%q, %d) = %q，解析回 %d (%v)", format, int64(m), s, int64(parsed), err)
+			}
+		}
+	}
+
+	// 与旧版 float64 序列化结果逐字节一致
+	for _, tt := range []struct {
+		m    Money
+		want string
+	}{{150, "1.5"}, {100, "1"}, {1, "0.01"}, {-250, "-2.5"}, {0, "0"}} {
+		data, _ := json.Marshal(tt.m)
+		if string(data) != tt.want {
+			t.Fatalf("MarshalJSON(%d) = %s，期望 %s", int64(tt.m), data, tt.want)
+		}
+	}
+
+	var m Money
+	if err := json.Unmarshal([]byte(`"1.23"`), &m); err != nil || m != 123 {
+		t.Fatalf(`UnmarshalJSON("1.23") = %d (%v)，期望 123`, int64(m), err)
+	}
+	m = 5
+	if err := json.Unmarshal([]byte(`null`), &m); err != nil || m != 0 {
+		t.Fatalf("UnmarshalJSON(null) = %d (%v)，期望 0", int64(m), err)
+	}
+}
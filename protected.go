@@ -0,0 +1,131 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"log"
+	"os"
+	"sort"
+	"strings"
+)
+
+// writeGuard 受保护环境的写入确认选项
+type writeGuard struct {
+	Yes              bool // --yes：跳过交互确认
+	AllowDestructive bool // --allow-destructive：允许删除已有数据的操作（如 --on-conflict replace）
+}
+
+// protectedTarget 导入的目标环境是否受保护，env 为空时使用 default_env
+func protectedTarget(env string) (string, bool) {
+	config, err := LoadConfig(configPath)
+	if err != nil {
+		log.Fatalf("❌ 加载配置失败: %v", err)
+	}
+	if env == "" {
+		env = config.DefaultEnv
+	}
+	name := ResolveEnv(env)
+	envConfig, ok := config.Environments[name]
+	return name, ok && envConfig.IsProtected()
+}
+
+// runGuardedImport 执行导入；目标为受保护环境时先以 dry-run 生成写入计划并汇总，
+// 经交互确认（或 --yes）后才真正写入。破坏性操作在受保护环境上需显式 --allow-destructive
+func runGuardedImport(env string, options ImportOptions, guard writeGuard, run func(ImportOptions)) {
+	name, protected := protectedTarget(env)
+	if !protected || options.DryRun {
+		run(options)
+		return
+	}
+
+	fmt.Printf("🛡️ 目标环境 %s 为受保护环境，先生成写入计划\n", name)
+	if options.Policy == ImportPolicyReplace && !guard.AllowDestructive {
+		log.Fatalf("❌ 受保护环境 %s 禁止 --on-conflict replace（会删除已有切片），确需执行请加 --allow-destructive", name)
+	}
+
+	planOptions := options
+	planOptions.DryRun = true
+	planOptions.Plan = &importPlanTotals{}
+	run(planOptions)
+	plan := planOptions.Plan.Plan()
+
+	printProtectedPlan(name, options, plan)
+	if plan.Files == 0 {
+		fmt.Println("✅ 没有需要写入的文件，退出")
+		return
+	}
+	if plan.Conflicts > 0 && options.Policy == ImportPolicyFail {
+		log.Fatalf("❌ 计划中有 %d 个文件与已有切片冲突，当前冲突策略 fail 会导致导入中途失败，请使用 --on-conflict skip-existing 或确认后改用 replace", plan.Conflicts)
+	}
+	if !confirmProtectedWrite(name, guard.Yes) {
+		fmt.Println("🚫 已取消，未写入任何数据")
+		os.Exit(1)
+	}
+
+	fmt.Printf("\n🚀 开始写入受保护环境 %s\n", name)
+	run(options)
+}
+
+// printProtectedPlan 按目标表汇总写入计划：档位、文件数、写入条数、总投注与总中奖、被替换的已有记录数
+func printProtectedPlan(env string, options ImportOptions, plan importPlan) {
+	type tableSummary struct {
+		levels   map[float64]bool
+		files    int
+		rows     int
+		totalBet Money
+		totalWin Money
+		replaced int
+	}
+	tables := make(map[string]*tableSummary)
+	var names []string
+	for _, s := range plan.Slices {
+		t, ok := tables[s.TableName]
+		if !ok {
+			t = &tableSummary{levels: make(map[float64]bool)}
+			tables[s.TableName] = t
+			names = append(names, s.TableName)
+		}
+		t.levels[s.RtpLevel] = true
+		t.files++
+		t.rows += s.Rows
+		t.totalBet += s.TotalBet
+		t.totalWin += s.TotalWin
+		t.replaced += s.Replaced
+	}
+	sort.Strings(names)
+
+	fmt.Printf("\n🛡️ ===== 受保护环境 %s 写入计划 =====\n", env)
+	fmt.Printf("冲突策略: %s, 事务范围: %s\n", options.Policy, options.Scope)
+	for _, name := range names {
+		t := tables[name]
+		levels := make([]float64, 0, len(t.levels))
+		for level := range t.levels {
+			levels = append(levels, level)
+		}
+		sort.Float64s(levels)
+		levelStrs := make([]string, len(levels))
+		for i, level := range levels {
+			levelStrs[i] = fmt.Sprintf("%g", level)
+		}
+		fmt.Printf("  %s: 档位 [%s], %d 个文件, 写入 %d 条, 总投注 %s, 总中奖 %s, RTP %.6f, 替换已有 %d 条\n",
+			name, strings.Join(levelStrs, " "), t.files, t.rows, t.totalBet, t.totalWin, t.totalWin.Ratio(t.totalBet), t.replaced)
+	}
+	fmt.Printf("合计: %d 张表, %d 个文件, 写入 %d 条, 总投注 %s, 总中奖 %s, RTP %.6f, 替换已有 %d 条; %d 个文件与已有切片冲突, %d 个文件按导入清单跳过\n",
+		len(names), plan.Files, plan.Rows, plan.TotalBet, plan.TotalWin, plan.TotalWin.Ratio(plan.TotalBet), plan.Replaced, plan.Conflicts, plan.Unchanged)
+}
+
+// confirmProtectedWrite 受保护环境写入确认：--yes 直接通过，否则需在终端中输入环境名
+// 标准输入不是终端（如脚本、CI）时不会等待输入，直接拒绝
+func confirmProtectedWrite(env string, yes bool) bool {
+	if yes {
+		fmt.Println("✅ 已指定 --yes，跳过交互确认")
+		return true
+	}
+	if st, err := os.Stdin.Stat(); err != nil || st.Mode()&os.ModeCharDevice == 0 {
+		fmt.Println("❌ 标准输入不是终端，无法交互确认；确认计划无误后请加 --yes 重新执行")
+		return false
+	}
+	fmt.Printf("⚠️ 请输入环境名 %s 确认写入: ", env)
+	answer, _ := bufio.NewReader(os.Stdin).ReadString('\n')
+	return strings.TrimSpace(answer) == env
+}
@@ -0,0 +1,192 @@
+package main
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// readResultFileHeader 流式读取生成文件头部，读到 data 字段即停止，不加载数据部分
+func readResultFileHeader(path string) (*ResultFileHeader, error) {
+	fh, err := openResultFile(path)
+	if err != nil {
+		return nil, err
+	}
+	defer fh.Close()
+	return readResultHeader(json.NewDecoder(fh))
+}
+
+// readResultContent 读取结果文件的完整内容，.json.gz 返回解压后的内容
+func readResultContent(path string) ([]byte, error) {
+	fh, err := openResultFile(path)
+	if err != nil {
+		return nil, err
+	}
+	defer fh.Close()
+	return io.ReadAll(fh)
+}
+
+// findOriginalResultFile 查找原文件：按当前 settings.output.gzip 的扩展名优先，另一种扩展名的文件存在时使用它
+func findOriginalResultFile(config *Config, dir string, rtpNo float64, srNumber int) string {
+	preferred := filepath.Join(dir, resultFileName(config, rtpNo, srNumber))
+	if _, err := os.Stat(preferred); err == nil {
+		return preferred
+	}
+	other := *config
+	other.Settings.Output.Gzip = !config.Settings.Output.Gzip
+	alt := filepath.Join(dir, resultFileName(&other, rtpNo, srNumber))
+	if _, err := os.Stat(alt); err == nil {
+		return alt
+	}
+	return preferred
+}
+
+// regenerateTask 重建单个生成文件所需的信息，由原文件头部与命令行参数确定
+type regenerateTask struct {
+	config          *Config
+	strategy        SelectionStrategy
+	level           RtpLevel
+	srNumber        int
+	seed            int64
+	originalPath    string
+	regeneratedPath string
+}
+
+// runRegenerateMode 按文件中记录的任务种子重建单个生成文件
+// modeArg、seedArg 为空时读取原文件记录；重建结果写入 output/regenerate/<gameId>[_fb]/，并与原文件逐字节比对
+func runRegenerateMode(gameID int, rtpNo float64, srNumber int, isFb bool, modeArg string, seedArg string, hasSeed bool) {
+	config, err := LoadConfig(configPath)
+	if err != nil {
+		log.Fatalf("加载配置文件失败: %v", err)
+	}
+	task, err := planRegenerate(config.ForGameID(gameID), rtpNo, srNumber, isFb, modeArg, seedArg, hasSeed)
+	if err != nil {
+		log.Fatalf("❌ %v", err)
+	}
+
+	fmt.Printf("🔁 重建文件 | 游戏%d | 模式 %s | RTP等级 %.0f | 第%d次 | 种子 %d\n", gameID, task.strategy.Name(), rtpNo, srNumber, task.seed)
+
+	db, err := NewDatabase(task.config, "")
+	if err != nil {
+		log.Fatalf("数据库连接失败: %v", err)
+	}
+	defer db.Close()
+
+	if err := task.run(db); err != nil {
+		log.Fatalf("❌ %v", err)
+	}
+
+	original, regenerated, err := task.contents()
+	if os.IsNotExist(err) {
+		fmt.Printf("⚠️ 原文件 %s 不存在，跳过比对，重建结果: %s\n", task.originalPath, task.regeneratedPath)
+		return
+	}
+	if err != nil {
+		log.Fatalf("❌ %v", err)
+	}
+	if bytes.Equal(original, regenerated) {
+		fmt.Printf("✅ 重建结果与原文件逐字节一致 (sha256: %x)\n", sha256.Sum256(regenerated))
+		return
+	}
+	fmt.Printf("❌ 重建结果与原文件不一致\n")
+	fmt.Printf("   原文件: %s (sha256: %x)\n", task.originalPath, sha256.Sum256(original))
+	fmt.Printf("   重建件: %s (sha256: %x)\n", task.regeneratedPath, sha256.Sum256(regenerated))
+	fmt.Println("   请确认源表数据与配置未发生变化")
+	os.Exit(1)
+}
+
+// planRegenerate 确定重建所用的模式、种子与档位，config 为该游戏的配置
+func planRegenerate(config *Config, rtpNo float64, srNumber int, isFb bool, modeArg string, seedArg string, hasSeed bool) (*regenerateTask, error) {
+	gameID := config.Game.ID
+	originalPath := findOriginalResultFile(config, gameOutputDir(gameID, isFb), rtpNo, srNumber)
+	// 重建文件与原文件使用相同的压缩格式
+	gameConfig := *config
+	gameConfig.Settings.Output.Gzip = strings.HasSuffix(originalPath, gzipFileExt)
+	config = &gameConfig
+
+	// 默认从原文件读取种子和模式，命令行参数优先
+	mode := ""
+	var seed int64
+	seedKnown := false
+	if header, err := readResultFileHeader(originalPath); err == nil {
+		mode = header.Mode
+		seed = header.Seed
+		seedKnown = header.HasSeed
+	} else if !os.IsNotExist(err) {
+		return nil, fmt.Errorf("读取原文件头部失败: %v", err)
+	}
+	if modeArg != "" {
+		mode = modeArg
+	}
+	if hasSeed {
+		var err error
+		seed, err = strconv.ParseInt(seedArg, 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("无效的种子: %s", seedArg)
+		}
+		seedKnown = true
+	}
+	if mode == "" {
+		if isFb {
+			mode = "generateFb"
+		} else {
+			mode = "generate"
+		}
+	}
+	if !seedKnown {
+		return nil, fmt.Errorf("原文件 %s 未记录种子，请通过 --seed 指定", originalPath)
+	}
+	strategy, ok := LookupStrategy(mode)
+	if !ok {
+		return nil, fmt.Errorf("不支持的生成模式: %s（支持: %s）", mode, strings.Join(StrategyNames(), ", "))
+	}
+	if strategy.Shape(config).IsFb != isFb {
+		return nil, fmt.Errorf("模式 %s 与目录不匹配，购买夺宝文件请使用 --fb", mode)
+	}
+	level, ok := findRtpLevel(strategy.Levels(config), rtpNo)
+	if !ok {
+		return nil, fmt.Errorf("模式 %s 的档位表中不存在档位 %.0f", mode, rtpNo)
+	}
+	outputDir := filepath.Join(outputRoot, "regenerate", filepath.Base(gameOutputDir(gameID, isFb)))
+	return &regenerateTask{
+		config:          config,
+		strategy:        strategy,
+		level:           level,
+		srNumber:        srNumber,
+		seed:            seed,
+		originalPath:    originalPath,
+		regeneratedPath: filepath.Join(outputDir, filepath.Base(originalPath)),
+	}, nil
+}
+
+// run 从 db 读取候选数据池并写出重建文件，db 需绑定到该游戏的配置
+func (t *regenerateTask) run(db *Database) error {
+	pools, err := t.strategy.LoadPools(db)
+	if err != nil {
+		return err
+	}
+	if _, err := runStrategyTask(t.config, t.strategy, pools, t.level, t.srNumber, t.seed, filepath.Dir(t.regeneratedPath)); err != nil {
+		return fmt.Errorf("重建失败: %v", err)
+	}
+	return nil
+}
+
+// contents 读取原文件与重建文件的内容，.json.gz 返回解压后的内容；原文件不存在时返回 os.IsNotExist 错误
+func (t *regenerateTask) contents() (original, regenerated []byte, err error) {
+	original, err = readResultContent(t.originalPath)
+	if err != nil {
+		return nil, nil, err
+	}
+	regenerated, err = readResultContent(t.regeneratedPath)
+	if err != nil {
+		return nil, nil, fmt.Errorf("读取重建文件失败: %v", err)
+	}
+	return original, regenerated, nil
+}
@@ -0,0 +1,88 @@
+package main
+
+import (
+	"bytes"
+	"testing"
+)
+
+// assertRegeneratesIdentically 按原文件头部记录的模式与种子重建游戏的每个生成文件，并与原文件逐字节比对
+func assertRegeneratesIdentically(t *testing.T, db *Database, config *Config, files []ManifestFile) {
+	t.Helper()
+	if len(files) == 0 {
+		t.Fatalf("游戏 %d 没有生成文件", config.Game.ID)
+	}
+	for _, f := range files {
+		task, err := planRegenerate(config, f.RtpLevel, f.SrNumber, false, "", "", false)
+		if err != nil {
+			t.Fatalf("游戏 %d 文件 %s 准备重建失败: %v", config.Game.ID, f.Name, err)
+		}
+		if err := task.run(db.ForConfig(task.config)); err != nil {
+			t.Fatalf("游戏 %d 文件 %s 重建失败: %v", config.Game.ID, f.Name, err)
+		}
+		original, regenerated, err := task.contents()
+		if err != nil {
+			t.Fatalf("游戏 %d 文件 %s 读取失败: %v", config.Game.ID, f.Name, err)
+		}
+		if !bytes.Equal(original, regenerated) {
+			t.Fatalf("游戏 %d 文件 %s 重建结果与原文件不一致", config.Game.ID, f.Name)
+		}
+	}
+}
+
+func TestRegenerateRoundTrip(t *testing.T) {
+	tables := map[string][]GameResultData{
+		"src_101": fakeSourceRows(1, 300, 200),
+		"src_102": fakeSourceRows(2, 300, 200),
+	}
+
+	t.Run("单游戏", func(t *testing.T) {
+		useOutputRoot(t)
+		config := testGenerationConfig(101)
+		config.MultiGame.Enabled = false
+		config.MultiGame.Games = nil
+		db, _ := newFakeDatabase(t, config, tables)
+
+		strategy, _ := LookupStrategy("generate")
+		scheduler := newGenerationScheduler(db, config, 7)
+		scheduler.AddGame(strategy, config)
+		if errs := scheduler.Run(); len(errs) > 0 {
+			t.Fatalf("生成失败: %v", errs)
+		}
+		assertRegeneratesIdentically(t, db, config, scheduler.games[0].files)
+	})
+
+	t.Run("多游戏", func(t *testing.T) {
+		useOutputRoot(t)
+		config := testGenerationConfig(101, 102)
+		db, _ := newFakeDatabase(t, config, tables)
+
+		strategy, _ := LookupStrategy("generate")
+		scheduler := newGenerationScheduler(db, config, 7)
+		for _, game := range config.MultiGame.Games {
+			scheduler.AddGame(strategy, config.ForGame(game))
+		}
+		if errs := scheduler.Run(); len(errs) > 0 {
+			t.Fatalf("生成失败: %v", errs)
+		}
+		for _, g := range scheduler.games {
+			assertRegeneratesIdentically(t, db, config.ForGameID(g.config.Game.ID), g.files)
+		}
+	})
+
+	t.Run("压缩输出", func(t *testing.T) {
+		useOutputRoot(t)
+		config := testGenerationConfig(102)
+		config.Settings.Output.Gzip = true
+		db, _ := newFakeDatabase(t, config, tables)
+
+		strategy, _ := LookupStrategy("generate")
+		scheduler := newGenerationScheduler(db, config, 7)
+		scheduler.AddGame(strategy, config.ForGameID(102))
+		if errs := scheduler.Run(); len(errs) > 0 {
+			t.Fatalf("生成失败: %v", errs)
+		}
+		// 重建时按原文件的扩展名选择压缩格式，与当前配置无关
+		config.Settings.Output.Gzip = false
+		assertRegeneratesIdentically(t, db, config.ForGameID(102), scheduler.games[0].files)
+	})
+}
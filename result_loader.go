@@ -0,0 +1,349 @@
+package main
+
+import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"io"
+	"math"
+
+	"github.com/lib/pq"
+)
+
+// resultColumns 目标表的写入列，顺序与 COPY 每行的值一致
+var resultColumns = []string{"rtpLevel", "srNumber", "srId", "bet", "win", "detail"}
+
+// defaultProgressRows 未配置 settings.batch_size 时每写入多少条打印一次进度
+const defaultProgressRows = 100000
+
+// ensureResultTable 创建目标数据表、索引与 (rtpLevel, srNumber, srId) 唯一索引
+// import、importFb 与 import-s3 共用同一表结构
+func ensureResultTable(db *Database, tableName string) error {
+	query := fmt.Sprintf(`
+		CREATE TABLE IF NOT EXISTS "%s" (
+			"id" SERIAL PRIMARY KEY,
+			"rtpLevel" REAL NOT NULL,
+			"srNumber" INTEGER NOT NULL,
+			"srId" SERIAL NOT NULL,
+			"bet" NUMERIC NOT NULL,
+			"win" NUMERIC NOT NULL,
+			"detail" JSONB,
+			"created_at" TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+		);
+	`, tableName)
+	if _, err := db.DB.Exec(query); err != nil {
+		return fmt.Errorf("创建表失败: %v", err)
+	}
+
+	indexQueries := []string{
+		fmt.Sprintf(`CREATE INDEX IF NOT EXISTS "%s_rtpLevel_idx" ON "%s" ("rtpLevel")`, tableName, tableName),
+		fmt.Sprintf(`CREATE INDEX IF NOT EXISTS "%s_srNumber_idx" ON "%s" ("srNumber")`, tableName, tableName),
+		fmt.Sprintf(`CREATE INDEX IF NOT EXISTS "%s_srId_idx" ON "%s" ("srId")`, tableName, tableName),
+		fmt.Sprintf(`CREATE INDEX IF NOT EXISTS "%s_rtpLevel_srNumber_idx" ON "%s" ("rtpLevel", "srNumber")`, tableName, tableName),
+		fmt.Sprintf(`CREATE INDEX IF NOT EXISTS "%s_detail_gin_idx" ON "%s" USING GIN ("detail")`, tableName, tableName),
+	}
+	for _, indexSQL := range indexQueries {
+		if _, err := db.DB.Exec(indexSQL); err != nil {
+			return fmt.Errorf("创建索引失败: %v", err)
+		}
+	}
+	if err := ensureSliceUniqueIndex(db, tableName); err != nil {
+		return err
+	}
+	// 旧版本创建的同列非唯一索引与唯一索引重复，唯一索引就绪后删除
+	dropQuery := fmt.Sprintf(`DROP INDEX IF EXISTS "%s_rtpLevel_srNumber_srId_idx"`, tableName)
+	if _, err := db.DB.Exec(dropQuery); err != nil {
+		return fmt.Errorf("删除重复索引失败: %v", err)
+	}
+
+	fmt.Printf("✅ 目标表已就绪: %s\n", tableName)
+	return nil
+}
+
+// ResultFileHeader 结果文件中 data 数组之前的头部字段
+type ResultFileHeader struct {
+	RtpLevel int
+	SrNumber int
+	Seed     int64
+	HasSeed  bool
+	Mode     string
+}
+
+// readResultHeader 解析结果文件头部，返回时 decoder 已进入 data 数组内部
+func readResultHeader(dec *json.Decoder) (*ResultFileHeader, error) {
+	tok, err := dec.Token()
+	if err != nil {
+		return nil, fmt.Errorf("读取对象开始标记失败: %v", err)
+	}
+	if delim, ok := tok.(json.Delim); !ok || delim != '{' {
+		return nil, fmt.Errorf("JSON格式错误: 缺少对象开始")
+	}
+
+	header := &ResultFileHeader{}
+	for dec.More() {
+		tok, err := dec.Token()
+		if err != nil {
+			return nil, fmt.Errorf("解析JSON token失败: %v", err)
+		}
+		key, _ := tok.(string)
+		switch key {
+		case "rtpLevel":
+			// 兼容旧文件中 15.0 形式的档位
+			var v float64
+			if err := dec.Decode(&v); err != nil {
+				return nil, fmt.Errorf("解析rtpLevel失败: %v", err)
+			}
+			header.RtpLevel = int(math.Round(v))
+		case "srNumber":
+			if err := dec.Decode(&header.SrNumber); err != nil {
+				return nil, fmt.Errorf("解析srNumber失败: %v", err)
+			}
+		case "seed":
+			if err := dec.Decode(&header.Seed); err != nil {
+				return nil, fmt.Errorf("解析seed失败: %v", err)
+			}
+			header.HasSeed = true
+		case "mode":
+			if err := dec.Decode(&header.Mode); err != nil {
+				return nil, fmt.Errorf("解析mode失败: %v", err)
+			}
+		case "data":
+			tok, err := dec.Token()
+			if err != nil {
+				return nil, fmt.Errorf("读取data数组开始标记失败: %v", err)
+			}
+			if delim, ok := tok.(json.Delim); !ok || delim != '[' {
+				return nil, fmt.Errorf("期望数组开始标记 '['，但得到 %v", tok)
+			}
+			return header, nil
+		default:
+			var skip json.RawMessage
+			if err := dec.Decode(&skip); err != nil {
+				return nil, fmt.Errorf("跳过字段 %s 失败: %v", key, err)
+			}
+		}
+	}
+	return nil, fmt.Errorf("文件中缺少 data 数组")
+}
+
+// readResultTrailer 读取 data 数组结束标记以及其后的字段，直至对象结束
+func readResultTrailer(dec *json.Decoder) error {
+	tok, err := dec.Token()
+	if err != nil {
+		return fmt.Errorf("读取数组结束标记失败: %v", err)
+	}
+	if delim, ok := tok.(json.Delim); !ok || delim != ']' {
+		return fmt.Errorf("期望数组结束标记 ']'，但得到 %v", tok)
+	}
+	for dec.More() {
+		if _, err := dec.Token(); err != nil {
+			return fmt.Errorf("解析JSON token失败: %v", err)
+		}
+		var skip json.RawMessage
+		if err := dec.Decode(&skip); err != nil {
+			return fmt.Errorf("跳过尾部字段失败: %v", err)
+		}
+	}
+	tok, err = dec.Token()
+	if err != nil {
+		return fmt.Errorf("读取对象结束标记失败: %v", err)
+	}
+	if delim, ok := tok.(json.Delim); !ok || delim != '}' {
+		return fmt.Errorf("JSON格式错误: 缺少对象结束")
+	}
+	return nil
+}
+
+// resultSlice 一个 (rtpLevel, srNumber) 切片的写入目标
+type resultSlice struct {
+	TableName string
+	RtpLevel  float64
+	SrNumber  int
+	FixedBet  Money // 非零时覆盖每行的 tb，例如 importFb 按配置的购买下注额写入
+}
+
+// copyResultRows 通过 COPY 将 data 数组中剩余的数据项流式写入切片，srId 在文件内从 1 开始连续
+// 不再按绑定参数上限拆分批次，progressRows 仅控制进度输出频率
+func copyResultRows(tx *sql.Tx, dec *json.Decoder, slice resultSlice, progressRows int) (int, error) {
+	stmt, err := tx.Prepare(pq.CopyIn(slice.TableName, resultColumns...))
+	if err != nil {
+		return 0, fmt.Errorf("准备COPY失败: %v", err)
+	}
+	defer stmt.Close()
+
+	count := 0
+	for dec.More() {
+		var item ResultRow
+		if err := dec.Decode(&item); err != nil {
+			return count, fmt.Errorf("解析数据项 %d 失败: %v", count+1, err)
+		}
+		count++
+
+		bet := item.TB
+		if slice.FixedBet != 0 {
+			bet = slice.FixedBet
+		}
+		if _, err := stmt.Exec(slice.RtpLevel, slice.SrNumber, count, bet, item.AW, item.Detail()); err != nil {
+			return count, fmt.Errorf("写入记录 %d 失败: %v", count, err)
+		}
+		if progressRows > 0 && count%progressRows == 0 {
+			fmt.Printf("    🔄 已写入 %d 条记录\n", count)
+		}
+	}
+
+	// 无参数 Exec 结束 COPY 并等待服务端确认
+	if _, err := stmt.Exec(); err != nil {
+		return count, fmt.Errorf("完成COPY失败: %v", err)
+	}
+	if err := stmt.Close(); err != nil {
+		return count, fmt.Errorf("关闭COPY失败: %v", err)
+	}
+	return count, nil
+}
+
+// importSession 一次导入的事务范围：默认每个文件一个事务；--atomic game 时整个游戏目录共用一个事务
+// 无论哪种范围，目标表中都不会出现只写入一部分的 (rtpLevel, srNumber) 切片
+type importSession struct {
+	db           *Database
+	options      ImportOptions
+	progressRows int
+	tx           *sql.Tx // 按游戏目录原子导入时共享的事务
+	plan         importPlan
+}
+
+// newImportSession 创建导入会话
+func newImportSession(db *Database, config *Config, options ImportOptions) *importSession {
+	return &importSession{
+		db:           db,
+		options:      options,
+		progressRows: progressRowsFor(config),
+	}
+}
+
+// Begin 开始一个游戏目录的导入；按游戏原子导入时开启共享事务
+func (s *importSession) Begin() error {
+	if s.options.Scope != ImportScopeGame || s.options.DryRun {
+		return nil
+	}
+	tx, err := s.db.BeginWithRetry()
+	if err != nil {
+		return fmt.Errorf("开始事务失败: %v", err)
+	}
+	s.tx = tx
+	fmt.Println("🔒 整个游戏目录在同一事务中导入，任一文件失败将全部回滚")
+	return nil
+}
+
+// Commit 提交共享事务；按文件导入时每个文件已单独提交，--dry-run 时输出计划汇总
+func (s *importSession) Commit() error {
+	if s.options.DryRun {
+		s.printPlanSummary()
+		return nil
+	}
+	if s.tx == nil {
+		return nil
+	}
+	tx := s.tx
+	s.tx = nil
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("提交事务失败: %v", err)
+	}
+	fmt.Println("✅ 游戏目录事务已提交")
+	return nil
+}
+
+// Rollback 放弃共享事务中尚未提交的全部写入，已提交或按文件导入时无操作
+func (s *importSession) Rollback() {
+	if s.tx == nil {
+		return
+	}
+	s.tx.Rollback()
+	s.tx = nil
+	fmt.Println("↩️  游戏目录事务已回滚")
+}
+
+// ImportFile 导入一个结果文件：导入清单中已以相同校验和完成的文件直接跳过，不会打开文件流
+// 其余文件先在清单中登记，数据与完成状态在同一事务中提交，失败时记录错误以便重跑时重试
+func (s *importSession) ImportFile(src importSource, open func() (io.ReadCloser, error), target func(*ResultFileHeader) resultSlice) (int, bool, error) {
+	if s.options.DryRun {
+		return s.planFile(src, open, target)
+	}
+
+	done, err := manifestCompleted(s.db, src)
+	if err != nil {
+		return 0, false, err
+	}
+	if done {
+		fmt.Printf("  ⏭️  %s 已导入到 %s 且校验和未变化，跳过\n", src.Source, src.TargetTable)
+		return 0, true, nil
+	}
+	if err := markManifestStarted(s.db, src); err != nil {
+		return 0, false, err
+	}
+
+	count, skipped, err := s.importStream(src, open, target)
+	if err != nil {
+		markManifestFinished(s.db, src, manifestStatusFailed, err.Error())
+		return count, false, err
+	}
+	if skipped {
+		markManifestFinished(s.db, src, manifestStatusSkipped, "")
+	}
+	return count, skipped, nil
+}
+
+// importStream 从结果文件流导入一个切片：解析头部、按冲突策略处理已有数据、COPY 写入
+// target 根据文件头部确定写入的表、rtpLevel 与 srNumber；返回写入条数以及是否因 skip-existing 跳过
+func (s *importSession) importStream(src importSource, open func() (io.ReadCloser, error), target func(*ResultFileHeader) resultSlice) (int, bool, error) {
+	r, err := open()
+	if err != nil {
+		return 0, false, err
+	}
+	defer r.Close()
+
+	dec := json.NewDecoder(r)
+	header, err := readResultHeader(dec)
+	if err != nil {
+		return 0, false, err
+	}
+	slice := target(header)
+	fmt.Printf("  📊 切片: rtpLevel=%g, srNumber=%d\n", slice.RtpLevel, slice.SrNumber)
+
+	tx := s.tx
+	if tx == nil {
+		tx, err = s.db.BeginWithRetry()
+		if err != nil {
+			return 0, false, fmt.Errorf("开始事务失败: %v", err)
+		}
+		defer tx.Rollback()
+	}
+
+	skip, err := prepareSlice(tx, slice.TableName, slice.RtpLevel, slice.SrNumber, s.options.Policy)
+	if err != nil || skip {
+		return 0, skip, err
+	}
+	count, err := copyResultRows(tx, dec, slice, s.progressRows)
+	if err != nil {
+		return count, false, err
+	}
+	if err := readResultTrailer(dec); err != nil {
+		return count, false, err
+	}
+	if err := markManifestCompleted(tx, src, slice, count); err != nil {
+		return count, false, err
+	}
+	if s.tx == nil {
+		if err := tx.Commit(); err != nil {
+			return count, false, fmt.Errorf("提交事务失败: %v", err)
+		}
+	}
+	return count, false, nil
+}
+
+// progressRowsFor 进度输出间隔，沿用 settings.batch_size 配置
+func progressRowsFor(config *Config) int {
+	if config.Settings.BatchSize > 0 {
+		return config.Settings.BatchSize
+	}
+	return defaultProgressRows
+}
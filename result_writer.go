@@ -0,0 +1,213 @@
+package main
+
+import (
+	"bufio"
+	"compress/gzip"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"hash"
+	"io"
+	"os"
+	"path"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// 结果文件扩展名：settings.output.gzip 为 true 时生成 .json.gz
+const (
+	jsonFileExt = ".json"
+	gzipFileExt = ".json.gz"
+)
+
+// resultFileName 结果文件名：<output_table_prefix><档位>_<第几次>.json[.gz]
+func resultFileName(config *Config, rtpLevel float64, testNumber int) string {
+	ext := jsonFileExt
+	if config.Settings.Output.Gzip {
+		ext = gzipFileExt
+	}
+	return fmt.Sprintf("%s%.0f_%d%s", config.Tables.OutputTablePrefix, rtpLevel, testNumber, ext)
+}
+
+// resultFileRegexp 结果文件名正则：<output_table_prefix><档位>_<第几次>.json[.gz]，生成、校验、导入与上传共用
+func resultFileRegexp(config *Config) *regexp.Regexp {
+	return regexp.MustCompile(`^` + regexp.QuoteMeta(config.Tables.OutputTablePrefix) + `(\d+)_(\d+)\.json(\.gz)?$`)
+}
+
+// parseResultFileName 按 resultFileRegexp 解析档位与第几次，不符合命名规则时 ok 为 false
+func parseResultFileName(re *regexp.Regexp, name string) (rtpLevel, testNum int, ok bool) {
+	m := re.FindStringSubmatch(name)
+	if m == nil {
+		return 0, 0, false
+	}
+	rtpLevel, _ = strconv.Atoi(m[1])
+	testNum, _ = strconv.Atoi(m[2])
+	return rtpLevel, testNum, true
+}
+
+// isResultFileName 是否为结果文件（.json 或 .json.gz），生成清单 manifest.json 除外
+func isResultFileName(name string) bool {
+	if path.Base(name) == manifestFileName {
+		return false
+	}
+	lower := strings.ToLower(name)
+	return strings.HasSuffix(lower, jsonFileExt) || strings.HasSuffix(lower, gzipFileExt)
+}
+
+// trimResultFileExt 去掉 .json / .json.gz 扩展名
+func trimResultFileExt(name string) string {
+	if strings.HasSuffix(strings.ToLower(name), gzipFileExt) {
+		return name[:len(name)-len(gzipFileExt)]
+	}
+	return strings.TrimSuffix(name, jsonFileExt)
+}
+
+// gzipReadCloser 关闭时同时关闭解压器与底层流
+type gzipReadCloser struct {
+	*gzip.Reader
+	body io.Closer
+}
+
+func (g *gzipReadCloser) Close() error {
+	g.Reader.Close()
+	return g.body.Close()
+}
+
+// decompressResult 按文件名透明解压：.json.gz 返回流式解压的读取器，其余原样返回
+func decompressResult(name string, body io.ReadCloser) (io.ReadCloser, error) {
+	if !strings.HasSuffix(strings.ToLower(name), gzipFileExt) {
+		return body, nil
+	}
+	zr, err := gzip.NewReader(bufio.NewReaderSize(body, 1<<20))
+	if err != nil {
+		body.Close()
+		return nil, fmt.Errorf("读取gzip头失败: %v", err)
+	}
+	return &gzipReadCloser{Reader: zr, body: body}, nil
+}
+
+// openResultFile 打开本地结果文件，.json.gz 透明解压
+func openResultFile(path string) (io.ReadCloser, error) {
+	fh, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	return decompressResult(path, fh)
+}
+
+// resultFileWriter 流式写出结果文件：逐行写入同目录下的临时文件，Commit 时 fsync 并原子重命名为目标文件
+// 进程中途退出只会留下以 . 开头、不带 .json 后缀的临时文件，不会出现被截断的结果文件
+// 输出（.json.gz 为解压后的内容）与 json.Marshal 整个 {"rtpLevel","srNumber","seed","mode","data"} 对象逐字节一致
+type resultFileWriter struct {
+	path string
+	tmp  *os.File
+	zw   *gzip.Writer // 目标为 .json.gz 时的压缩层
+	fw   *bufio.Writer
+	w    *bufio.Writer
+	sum  hash.Hash // 落盘字节（.json.gz 为压缩后）的 sha256
+	rows int
+}
+
+// createResultFile 创建临时文件并写入文件头，返回时已进入 data 数组
+func createResultFile(path string, header ResultFileHeader) (*resultFileWriter, error) {
+	dir, name := filepath.Split(path)
+	tmp, err := os.CreateTemp(dir, "."+trimResultFileExt(name)+".partial-*")
+	if err != nil {
+		return nil, fmt.Errorf("创建临时文件失败: %v", err)
+	}
+	rw := &resultFileWriter{path: path, tmp: tmp, sum: sha256.New()}
+	rw.fw = bufio.NewWriterSize(io.MultiWriter(tmp, rw.sum), 1<<20)
+	rw.w = rw.fw
+	if strings.HasSuffix(strings.ToLower(name), gzipFileExt) {
+		// gzip 头不写入文件名与修改时间，相同内容压缩结果一致
+		rw.zw = gzip.NewWriter(rw.fw)
+		rw.w = bufio.NewWriterSize(rw.zw, 1<<20)
+	}
+
+	mode, err := json.Marshal(header.Mode)
+	if err != nil {
+		rw.Abort()
+		return nil, fmt.Errorf("序列化mode失败: %v", err)
+	}
+	if _, err := fmt.Fprintf(rw.w, `{"rtpLevel":%d,"srNumber":%d,"seed":%d,"mode":%s,"data":[`,
+		header.RtpLevel, header.SrNumber, header.Seed, mode); err != nil {
+		rw.Abort()
+		return nil, fmt.Errorf("写入文件头失败: %v", err)
+	}
+	return rw, nil
+}
+
+// WriteRow 追加一行数据
+func (rw *resultFileWriter) WriteRow(row ResultRow) error {
+	b, err := json.Marshal(row)
+	if err != nil {
+		return fmt.Errorf("JSON序列化失败: %v", err)
+	}
+	if rw.rows > 0 {
+		if err := rw.w.WriteByte(','); err != nil {
+			return fmt.Errorf("写入数据失败: %v", err)
+		}
+	}
+	if _, err := rw.w.Write(b); err != nil {
+		return fmt.Errorf("写入数据失败: %v", err)
+	}
+	rw.rows++
+	return nil
+}
+
+// Commit 写入结尾、fsync 后重命名为目标文件，并 fsync 所在目录使重命名落盘
+func (rw *resultFileWriter) Commit() error {
+	if _, err := rw.w.WriteString("]}"); err != nil {
+		rw.Abort()
+		return fmt.Errorf("写入文件尾失败: %v", err)
+	}
+	if err := rw.w.Flush(); err != nil {
+		rw.Abort()
+		return fmt.Errorf("写入数据失败: %v", err)
+	}
+	if rw.zw != nil {
+		if err := rw.zw.Close(); err != nil {
+			rw.Abort()
+			return fmt.Errorf("gzip压缩失败: %v", err)
+		}
+		if err := rw.fw.Flush(); err != nil {
+			rw.Abort()
+			return fmt.Errorf("写入数据失败: %v", err)
+		}
+	}
+	if err := rw.tmp.Chmod(0644); err != nil {
+		rw.Abort()
+		return fmt.Errorf("设置文件权限失败: %v", err)
+	}
+	if err := rw.tmp.Sync(); err != nil {
+		rw.Abort()
+		return fmt.Errorf("fsync失败: %v", err)
+	}
+	if err := rw.tmp.Close(); err != nil {
+		os.Remove(rw.tmp.Name())
+		return fmt.Errorf("关闭临时文件失败: %v", err)
+	}
+	if err := os.Rename(rw.tmp.Name(), rw.path); err != nil {
+		os.Remove(rw.tmp.Name())
+		return fmt.Errorf("重命名临时文件失败: %v", err)
+	}
+	if dir, err := os.Open(filepath.Dir(rw.path)); err == nil {
+		dir.Sync()
+		dir.Close()
+	}
+	return nil
+}
+
+// SHA256 目标文件的校验和（sha256:<hex>，与导入清单中本地文件的校验和一致），Commit 成功后有效
+func (rw *resultFileWriter) SHA256() string {
+	return "sha256:" + hex.EncodeToString(rw.sum.Sum(nil))
+}
+
+// Abort 放弃写入并删除临时文件，目标文件保持不变
+func (rw *resultFileWriter) Abort() {
+	rw.tmp.Close()
+	os.Remove(rw.tmp.Name())
+}
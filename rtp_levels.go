@@ -1,12 +1,31 @@
 package main
 
+import (
+	"fmt"
+	"math"
+)
+
 // RtpLevel RTP等级结构体
+// MinRtp/MaxRtp 为该档位单个文件的 RTP 验收区间，未配置（0）时使用策略的默认区间
 type RtpLevel struct {
-	RtpNo float64 `json:"rtpNo"`
-	Rtp   float64 `json:"rtp"`
+	RtpNo  float64 `json:"rtpNo" yaml:"rtpNo"`
+	Rtp    float64 `json:"rtp" yaml:"rtp"`
+	MinRtp float64 `json:"minRtp,omitempty" yaml:"minRtp"`
+	MaxRtp float64 `json:"maxRtp,omitempty" yaml:"maxRtp"`
+}
+
+// RtpLevelTables 档位表配置：normal 用于 generate/generate2，test 用于 generate3，fb 用于 generateFb
+// 未配置的表使用下方编译内置的默认档位
+type RtpLevelTables struct {
+	Normal []RtpLevel `yaml:"normal"`
+	Test   []RtpLevel `yaml:"test"`
+	Fb     []RtpLevel `yaml:"fb"`
 }
 
-// RtpLevels 标准RTP等级配置
+// maxSaneRtp 档位 RTP 的合理上限
+const maxSaneRtp = 10.0
+
+// RtpLevels 标准RTP等级配置（默认值）
 var RtpLevels = []RtpLevel{
 	{RtpNo: 1, Rtp: 0.6},
 	{RtpNo: 2, Rtp: 0.7},
@@ -27,7 +46,7 @@ var RtpLevels = []RtpLevel{
 	{RtpNo: 50, Rtp: 0.5},
 }
 
-// RtpLevelsTest 测试RTP等级配置
+// RtpLevelsTest 测试RTP等级配置（默认值）
 var RtpLevelsTest = []RtpLevel{
 	{RtpNo: 120, Rtp: 1.2},
 	{RtpNo: 150, Rtp: 1.5},
@@ -38,7 +57,7 @@ var RtpLevelsTest = []RtpLevel{
 	{RtpNo: 500, Rtp: 5.0},
 }
 
-// FbRtpLevels 购买夺宝RTP等级配置
+// FbRtpLevels 购买夺宝RTP等级配置（默认值）
 var FbRtpLevels = []RtpLevel{
 	{RtpNo: 1, Rtp: 0.6},
 	{RtpNo: 2, Rtp: 0.7},
@@ -65,3 +84,84 @@ var FbRtpLevels = []RtpLevel{
 	{RtpNo: 300, Rtp: 3.0},
 	{RtpNo: 500, Rtp: 5.0},
 }
+
+// findRtpLevel 在档位表中查找指定档位
+func findRtpLevel(levels []RtpLevel, rtpNo float64) (RtpLevel, bool) {
+	for _, level := range levels {
+		if level.RtpNo == rtpNo {
+			return level, true
+		}
+	}
+	return RtpLevel{}, false
+}
+
+// levelTable 按 multi_game.games 中当前游戏的覆盖 > 全局 rtp_levels > 内置默认 的顺序选取档位表
+func (c *Config) levelTable(pick func(RtpLevelTables) []RtpLevel, builtin []RtpLevel) []RtpLevel {
+	if game, ok := c.FindGame(c.Game.ID); ok {
+		if levels := pick(game.RtpLevels); len(levels) > 0 {
+			return levels
+		}
+	}
+	if levels := pick(c.RtpLevels); len(levels) > 0 {
+		return levels
+	}
+	return builtin
+}
+
+// NormalLevels 当前游戏的标准档位表
+func (c *Config) NormalLevels() []RtpLevel {
+	return c.levelTable(func(t RtpLevelTables) []RtpLevel { return t.Normal }, RtpLevels)
+}
+
+// TestLevels 当前游戏的测试档位表
+func (c *Config) TestLevels() []RtpLevel {
+	return c.levelTable(func(t RtpLevelTables) []RtpLevel { return t.Test }, RtpLevelsTest)
+}
+
+// FbLevels 当前游戏的购买夺宝档位表
+func (c *Config) FbLevels() []RtpLevel {
+	return c.levelTable(func(t RtpLevelTables) []RtpLevel { return t.Fb }, FbRtpLevels)
+}
+
+// validateLevelTable 校验单个档位表：档位号为正整数且不重复、RTP 在 (0, maxSaneRtp] 内、验收区间包含目标 RTP
+// 档位号会用作输出文件名（GameResults_<rtpNo>_<n>.json），因此必须为整数
+func validateLevelTable(path string, levels []RtpLevel) []string {
+	var problems []string
+	seen := make(map[float64]int)
+	for i, level := range levels {
+		itemPath := fmt.Sprintf("%s[%d]", path, i)
+		if level.RtpNo <= 0 || level.RtpNo != math.Trunc(level.RtpNo) {
+			problems = append(problems, fmt.Sprintf("%s.rtpNo: 档位号必须为正整数，当前为 %g", itemPath, level.RtpNo))
+		}
+		if first, dup := seen[level.RtpNo]; dup {
+			problems = append(problems, fmt.Sprintf("%s.rtpNo: 档位 %g 与 %s[%d] 重复", itemPath, level.RtpNo, path, first))
+		} else {
+			seen[level.RtpNo] = i
+		}
+		if level.Rtp <= 0 || level.Rtp > maxSaneRtp || math.IsNaN(level.Rtp) {
+			problems = append(problems, fmt.Sprintf("%s.rtp: RTP 必须在 (0, %g] 内，当前为 %g", itemPath, maxSaneRtp, level.Rtp))
+		}
+		if level.MinRtp < 0 || level.MinRtp > level.Rtp {
+			problems = append(problems, fmt.Sprintf("%s.minRtp: 必须在 [0, rtp=%g] 内，当前为 %g", itemPath, level.Rtp, level.MinRtp))
+		}
+		if level.MaxRtp < 0 || (level.MaxRtp > 0 && level.MaxRtp < level.Rtp) {
+			problems = append(problems, fmt.Sprintf("%s.maxRtp: 不能小于 rtp=%g，当前为 %g", itemPath, level.Rtp, level.MaxRtp))
+		}
+	}
+	return problems
+}
+
+// rtpLevelProblems 校验全局 rtp_levels 与 multi_game.games 中各游戏覆盖的档位表
+func (c *Config) rtpLevelProblems() []string {
+	var problems []string
+	check := func(prefix string, tables RtpLevelTables) {
+		problems = append(problems, validateLevelTable(prefix+".normal", tables.Normal)...)
+		problems = append(problems, validateLevelTable(prefix+".test", tables.Test)...)
+		problems = append(problems, validateLevelTable(prefix+".fb", tables.Fb)...)
+	}
+	check("rtp_levels", c.RtpLevels)
+	for i, game := range c.MultiGame.Games {
+		check(fmt.Sprintf("multi_game.games[%d].rtp_levels", i), game.RtpLevels)
+	}
+	return problems
+}
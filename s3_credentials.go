@@ -0,0 +1,112 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	awsconfig "github.com/aws/aws-sdk-go-v2/config"
+	"github.com/aws/aws-sdk-go-v2/credentials"
+	"github.com/aws/aws-sdk-go-v2/credentials/stscreds"
+	"github.com/aws/aws-sdk-go-v2/service/sts"
+)
+
+// S3凭据来源
+const (
+	s3CredentialsStatic      = "static"       // 配置文件或 AWS_ACCESS_KEY_ID / AWS_SECRET_ACCESS_KEY 中的静态密钥
+	s3CredentialsProfile     = "profile"      // ~/.aws/config 中的命名 profile（SSO、assume role、credential_process 等）
+	s3CredentialsDefault     = "default"      // AWS SDK 默认凭据链：环境变量、共享配置、web identity、ECS/EC2 实例角色
+	s3CredentialsWebIdentity = "web_identity" // 用 OIDC 令牌文件扮演 IAM 角色（EKS IRSA 等）
+)
+
+// S3CredentialSource 生效的S3凭据来源
+// 未配置时兼容旧配置：配置文件或环境变量中有静态密钥则用 static，否则走默认凭据链
+func (c *Config) S3CredentialSource() string {
+	if c.S3.Credentials != "" {
+		return c.S3.Credentials
+	}
+	if c.S3.AccessKeyID != "" || os.Getenv("AWS_ACCESS_KEY_ID") != "" {
+		return s3CredentialsStatic
+	}
+	return s3CredentialsDefault
+}
+
+// s3WebIdentitySettings web_identity 的角色、令牌文件与会话名，配置为空的项读取 SDK 约定的环境变量
+func (c *Config) s3WebIdentitySettings() (roleARN, tokenFile, sessionName string) {
+	roleARN, tokenFile, sessionName = c.S3.RoleARN, c.S3.WebIdentityTokenFile, c.S3.RoleSessionName
+	if roleARN == "" {
+		roleARN = os.Getenv("AWS_ROLE_ARN")
+	}
+	if tokenFile == "" {
+		tokenFile = os.Getenv("AWS_WEB_IDENTITY_TOKEN_FILE")
+	}
+	if sessionName == "" {
+		sessionName = os.Getenv("AWS_ROLE_SESSION_NAME")
+	}
+	return roleARN, tokenFile, sessionName
+}
+
+// loadS3AWSConfig 按凭据来源加载AWS配置
+func loadS3AWSConfig(config *Config) (aws.Config, error) {
+	source := config.S3CredentialSource()
+	opts := []func(*awsconfig.LoadOptions) error{awsconfig.WithRegion(config.S3.Region)}
+
+	switch source {
+	case s3CredentialsStatic:
+		// 优先级：环境变量 > .env文件 > 配置文件
+		accessKeyID := config.S3.AccessKeyID
+		secretAccessKey := config.S3.SecretAccessKey
+		if envAccessKey := os.Getenv("AWS_ACCESS_KEY_ID"); envAccessKey != "" {
+			accessKeyID = envAccessKey
+		}
+		if envSecretKey := os.Getenv("AWS_SECRET_ACCESS_KEY"); envSecretKey != "" {
+			secretAccessKey = envSecretKey
+		}
+		if accessKeyID == "" || secretAccessKey == "" {
+			return aws.Config{}, fmt.Errorf("凭据来源为 static，但未提供 access_key_id/secret_access_key（或 AWS_ACCESS_KEY_ID/AWS_SECRET_ACCESS_KEY）")
+		}
+		opts = append(opts, awsconfig.WithCredentialsProvider(credentials.NewStaticCredentialsProvider(
+			accessKeyID,
+			secretAccessKey,
+			"", // 非临时凭证无需会话令牌，临时凭证请使用 profile 或 default
+		)))
+	case s3CredentialsProfile:
+		if config.S3.Profile == "" {
+			return aws.Config{}, fmt.Errorf("凭据来源为 profile，但未配置 s3.profile")
+		}
+		opts = append(opts, awsconfig.WithSharedConfigProfile(config.S3.Profile))
+	case s3CredentialsDefault, s3CredentialsWebIdentity:
+	default:
+		return aws.Config{}, fmt.Errorf("无效的凭据来源 %s（可选 static/profile/default/web_identity）", source)
+	}
+
+	cfg, err := awsconfig.LoadDefaultConfig(context.TODO(), opts...)
+	if err != nil {
+		return aws.Config{}, err
+	}
+
+	if source == s3CredentialsWebIdentity {
+		roleARN, tokenFile, sessionName := config.s3WebIdentitySettings()
+		if roleARN == "" || tokenFile == "" {
+			return aws.Config{}, fmt.Errorf("凭据来源为 web_identity，但未配置 role_arn/web_identity_token_file（或 AWS_ROLE_ARN/AWS_WEB_IDENTITY_TOKEN_FILE）")
+		}
+		// AssumeRoleWithWebIdentity 不需要签名，STS 客户端直接使用基础配置
+		provider := stscreds.NewWebIdentityRoleProvider(sts.NewFromConfig(cfg), roleARN, stscreds.IdentityTokenFile(tokenFile),
+			func(o *stscreds.WebIdentityRoleOptions) {
+				o.RoleSessionName = sessionName
+			})
+		cfg.Credentials = aws.NewCredentialsCache(provider)
+	}
+
+	switch source {
+	case s3CredentialsProfile:
+		fmt.Printf("🔑 S3 凭据来源: %s (%s)\n", source, config.S3.Profile)
+	case s3CredentialsWebIdentity:
+		roleARN, _, _ := config.s3WebIdentitySettings()
+		fmt.Printf("🔑 S3 凭据来源: %s (%s)\n", source, roleARN)
+	default:
+		fmt.Printf("🔑 S3 凭据来源: %s\n", source)
+	}
+	return cfg, nil
+}
@@ -4,13 +4,11 @@ import (
 	"context"
 	"fmt"
 	"io"
-	"os"
+	"regexp"
 	"strconv"
 	"strings"
 
 	"github.com/aws/aws-sdk-go-v2/aws"
-	awsconfig "github.com/aws/aws-sdk-go-v2/config"
-	"github.com/aws/aws-sdk-go-v2/credentials"
 	"github.com/aws/aws-sdk-go-v2/service/s3"
 )
 
@@ -19,6 +17,7 @@ type S3FileInfo struct {
 	Key          string // S3对象键
 	Size         int64  // 文件大小
 	LastModified string // 最后修改时间
+	ETag         string // 对象ETag（去掉引号），用作导入清单的校验和
 	GameID       int    // 游戏ID
 	Mode         string // 模式：normal 或 fb
 	RtpLevel     int    // RTP等级
@@ -27,10 +26,20 @@ type S3FileInfo struct {
 
 // S3Client S3客户端
 type S3Client struct {
-	client *s3.Client
-	bucket string
+	client       *s3.Client
+	bucket       string
+	normalPrefix string         // 普通模式路径前缀模板
+	fbPrefix     string         // 购买夺宝模式路径前缀模板
+	fileNames    *regexp.Regexp // 结果文件名规则，见 resultFileRegexp
 }
 
+// S3路径前缀模板默认值，{gameId} 替换为游戏ID
+const (
+	s3GameIDPlaceholder   = "{gameId}"
+	defaultS3NormalPrefix = "mpg-slot-data/{gameId}/normal/"
+	defaultS3FbPrefix     = "mpg-slot-data/{gameId}/fb/"
+)
+
 // NewS3Client 创建S3客户端
 func NewS3Client(config *Config) (*S3Client, error) {
 	if !config.S3.Enabled {
@@ -43,47 +52,70 @@ func NewS3Client(config *Config) (*S3Client, error) {
 		fmt.Printf("⚠️  未找到.env文件，使用配置文件或环境变量: %v\n", err)
 	}
 
-	// 配置AWS客户端
-	// 优先级：环境变量 > .env文件 > 配置文件
-	accessKeyID := config.S3.AccessKeyID
-	secretAccessKey := config.S3.SecretAccessKey
-
-	// 检查环境变量
-	if envAccessKey := os.Getenv("AWS_ACCESS_KEY_ID"); envAccessKey != "" {
-		accessKeyID = envAccessKey
-	}
-	if envSecretKey := os.Getenv("AWS_SECRET_ACCESS_KEY"); envSecretKey != "" {
-		secretAccessKey = envSecretKey
-	}
-
-	cfg, err := awsconfig.LoadDefaultConfig(context.TODO(),
-		awsconfig.WithRegion(config.S3.Region),
-		awsconfig.WithCredentialsProvider(credentials.NewStaticCredentialsProvider(
-			accessKeyID,
-			secretAccessKey,
-			"", // 非临时凭证无需会话令牌
-		)),
-	)
+	cfg, err := loadS3AWSConfig(config)
 	if err != nil {
 		return nil, fmt.Errorf("无法加载AWS配置: %v", err)
 	}
 
-	// 创建S3客户端
-	client := s3.NewFromConfig(cfg)
+	// 创建S3客户端：配置了 endpoint 时连接 MinIO 等兼容 S3 的服务
+	client := s3.NewFromConfig(cfg, func(o *s3.Options) {
+		if config.S3.Endpoint != "" {
+			o.BaseEndpoint = aws.String(config.S3.Endpoint)
+		}
+		o.UsePathStyle = config.S3.UsePathStyle
+	})
+	if config.S3.Endpoint != "" {
+		fmt.Printf("🔗 S3 endpoint: %s (path-style: %t)\n", config.S3.Endpoint, config.S3.UsePathStyle)
+	}
 
+	normalPrefix, fbPrefix := config.S3PrefixTemplates()
 	return &S3Client{
-		client: client,
-		bucket: config.S3.Bucket,
+		client:       client,
+		bucket:       config.S3.Bucket,
+		normalPrefix: normalPrefix,
+		fbPrefix:     fbPrefix,
+		fileNames:    resultFileRegexp(config),
 	}, nil
 }
 
+// S3PrefixTemplates normal 与 fb 的路径前缀模板，未配置时使用默认值
+func (c *Config) S3PrefixTemplates() (string, string) {
+	normalPrefix, fbPrefix := c.S3.NormalPrefix, c.S3.FbPrefix
+	if normalPrefix == "" {
+		normalPrefix = defaultS3NormalPrefix
+	}
+	if fbPrefix == "" {
+		fbPrefix = defaultS3FbPrefix
+	}
+	return normalPrefix, fbPrefix
+}
+
+// GamePrefix 游戏在S3中的路径前缀，mode 为 normal 或 fb，import-s3 与 upload-s3 共用
+func (s3c *S3Client) GamePrefix(gameID int, mode string) string {
+	template := s3c.normalPrefix
+	if mode == "fb" {
+		template = s3c.fbPrefix
+	}
+	return expandS3Prefix(template, gameID)
+}
+
+// expandS3Prefix 展开路径前缀模板：替换 {gameId}，去掉开头的 /，并保证以 / 结尾
+func expandS3Prefix(template string, gameID int) string {
+	prefix := strings.ReplaceAll(template, s3GameIDPlaceholder, strconv.Itoa(gameID))
+	prefix = strings.TrimLeft(prefix, "/")
+	if !strings.HasSuffix(prefix, "/") {
+		prefix += "/"
+	}
+	return prefix
+}
+
 // CheckGameModes 检查游戏ID下有哪些模式的文件
 func (s3c *S3Client) CheckGameModes(gameID int) (bool, bool, error) {
 	hasNormal := false
 	hasFb := false
 
 	// 检查normal模式
-	normalPrefix := fmt.Sprintf("mpg-slot-data/%d/normal/", gameID)
+	normalPrefix := s3c.GamePrefix(gameID, "normal")
 	normalInput := &s3.ListObjectsV2Input{
 		Bucket:  aws.String(s3c.bucket),
 		Prefix:  aws.String(normalPrefix),
@@ -96,7 +128,7 @@ func (s3c *S3Client) CheckGameModes(gameID int) (bool, bool, error) {
 	hasNormal = len(normalResult.Contents) > 0
 
 	// 检查fb模式
-	fbPrefix := fmt.Sprintf("mpg-slot-data/%d/fb/", gameID)
+	fbPrefix := s3c.GamePrefix(gameID, "fb")
 	fbInput := &s3.ListObjectsV2Input{
 		Bucket:  aws.String(s3c.bucket),
 		Prefix:  aws.String(fbPrefix),
@@ -111,18 +143,13 @@ func (s3c *S3Client) CheckGameModes(gameID int) (bool, bool, error) {
 	return hasNormal, hasFb, nil
 }
 
-// ListS3Files 列出S3指定前缀下的所有JSON文件
+// ListS3Files 列出S3指定前缀下的所有JSON文件（.json 与 .json.gz）
 func (s3c *S3Client) ListS3Files(gameIDs []int, mode string) ([]S3FileInfo, error) {
 	var allFiles []S3FileInfo
 
 	for _, gameID := range gameIDs {
 		// 构建路径前缀
-		var prefix string
-		if mode == "fb" {
-			prefix = fmt.Sprintf("mpg-slot-data/%d/fb/", gameID)
-		} else {
-			prefix = fmt.Sprintf("mpg-slot-data/%d/normal/", gameID)
-		}
+		prefix := s3c.GamePrefix(gameID, mode)
 
 		fmt.Printf("🔍 正在搜索S3路径: %s\n", prefix)
 
@@ -143,16 +170,21 @@ func (s3c *S3Client) ListS3Files(gameIDs []int, mode string) ([]S3FileInfo, erro
 			// 收集JSON文件
 			for _, obj := range page.Contents {
 				key := *obj.Key
-				// 只处理JSON文件
-				if strings.HasSuffix(key, ".json") {
-					// 解析文件名获取RTP等级和测试编号
+				// 只处理JSON文件，.json.gz 在导入时流式解压
+				if isResultFileName(key) {
+					// 解析文件名获取RTP等级和测试编号，命名规则与本地导入、verify 相同
 					fileName := key[strings.LastIndex(key, "/")+1:]
-					rtpLevel, testNum := parseFileName(fileName)
+					rtpLevel, testNum, ok := parseResultFileName(s3c.fileNames, fileName)
+					if !ok {
+						fmt.Printf("⚠️ 跳过不符合命名规则的文件: %s\n", key)
+						continue
+					}
 
 					fileInfo := S3FileInfo{
 						Key:          key,
 						Size:         *obj.Size,
 						LastModified: obj.LastModified.Format("2006-01-02 15:04:05"),
+						ETag:         strings.Trim(aws.ToString(obj.ETag), `"`),
 						GameID:       gameID,
 						Mode:         mode,
 						RtpLevel:     rtpLevel,
@@ -189,38 +221,3 @@ func (s3c *S3Client) DownloadS3File(key string) ([]byte, error) {
 
 	return body, nil
 }
-
-// GetObjectStream 获取S3对象的流式读取器
-func (s3c *S3Client) GetObjectStream(key string) (*s3.GetObjectOutput, error) {
-	input := &s3.GetObjectInput{
-		Bucket: aws.String(s3c.bucket),
-		Key:    aws.String(key),
-	}
-
-	result, err := s3c.client.GetObject(context.TODO(), input)
-	if err != nil {
-		return nil, fmt.Errorf("获取S3对象流失败: %v", err)
-	}
-
-	return result, nil
-}
-
-// parseFileName 解析文件名获取RTP等级和测试编号
-// 例如: GameResults_50_1.json -> rtpLevel=50, testNum=1
-func parseFileName(fileName string) (int, int) {
-	// 移除.json后缀
-	name := strings.TrimSuffix(fileName, ".json")
-
-	// 按_分割
-	parts := strings.Split(name, "_")
-	if len(parts) >= 3 {
-		// 尝试解析RTP等级和测试编号
-		if rtpLevel, err := strconv.Atoi(parts[1]); err == nil {
-			if testNum, err := strconv.Atoi(parts[2]); err == nil {
-				return rtpLevel, testNum
-			}
-		}
-	}
-
-	return 0, 0
-}
@@ -0,0 +1,263 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+)
+
+// S3读取重试默认值
+const (
+	defaultS3RetryAttempts  = 5                // 连续失败的最大重试次数
+	defaultS3RetryBackoffMs = 500              // 首次重试前的等待时间，之后每次翻倍
+	maxS3RetryBackoff       = 30 * time.Second // 单次等待上限
+)
+
+// S3读取错误分类，用于导入汇总
+const (
+	s3ErrTransient = "网络/服务暂时性错误" // 连接中断、超时、5xx、限流，重试次数用尽后仍失败
+	s3ErrNotFound  = "对象不存在"
+	s3ErrDenied    = "无访问权限"
+	s3ErrModified  = "对象在导入期间被修改" // ETag 与列出对象时不一致（412），需要重新列出对象
+	s3ErrCanceled  = "已取消"
+	s3ErrRequest   = "S3 请求错误" // 其他不可重试的 S3 错误
+	importErrData  = "数据或写库错误" // 下载正常，解析文件或写入数据库失败
+)
+
+// s3FileError 导入单个S3文件失败的错误及其分类
+type s3FileError struct {
+	Class string
+	Err   error
+}
+
+func (e *s3FileError) Error() string { return e.Err.Error() }
+
+// importFailureClass 导入失败的分类，非 s3FileError 视为数据或写库错误
+func importFailureClass(err error) string {
+	var fe *s3FileError
+	if errors.As(err, &fe) {
+		return fe.Class
+	}
+	return importErrData
+}
+
+// classifyS3Error 按错误码、HTTP 状态码和网络错误类型对S3读取错误分类
+func classifyS3Error(err error) string {
+	if errors.Is(err, context.Canceled) {
+		return s3ErrCanceled
+	}
+	var apiErr interface{ ErrorCode() string }
+	if errors.As(err, &apiErr) {
+		switch apiErr.ErrorCode() {
+		case "NoSuchKey", "NotFound", "NoSuchBucket":
+			return s3ErrNotFound
+		case "AccessDenied", "Forbidden", "InvalidAccessKeyId", "SignatureDoesNotMatch", "ExpiredToken":
+			return s3ErrDenied
+		case "PreconditionFailed":
+			return s3ErrModified
+		case "SlowDown", "RequestTimeout", "RequestTimeTooSkewed", "InternalError", "ServiceUnavailable", "Throttling", "ThrottlingException":
+			return s3ErrTransient
+		}
+	}
+	// 请求未能发出或未收到响应（连接被拒绝、重置、EOF）
+	var connErr interface{ ConnectionError() bool }
+	if errors.As(err, &connErr) && connErr.ConnectionError() {
+		return s3ErrTransient
+	}
+	var httpErr interface{ HTTPStatusCode() int }
+	if errors.As(err, &httpErr) && httpErr.HTTPStatusCode() != 0 {
+		switch code := httpErr.HTTPStatusCode(); {
+		case code == 404:
+			return s3ErrNotFound
+		case code == 401 || code == 403:
+			return s3ErrDenied
+		case code == 412:
+			return s3ErrModified
+		case code == 408 || code == 429 || code >= 500:
+			return s3ErrTransient
+		default:
+			return s3ErrRequest
+		}
+	}
+	if apiErr != nil {
+		return s3ErrRequest
+	}
+	var netErr net.Error
+	if errors.As(err, &netErr) || errors.Is(err, io.ErrUnexpectedEOF) || errors.Is(err, syscall.ECONNRESET) ||
+		errors.Is(err, syscall.EPIPE) || errors.Is(err, context.DeadlineExceeded) {
+		return s3ErrTransient
+	}
+	// 读取响应体时的其他错误通常来自连接层
+	if strings.Contains(err.Error(), "connection reset") || strings.Contains(err.Error(), "broken pipe") {
+		return s3ErrTransient
+	}
+	return s3ErrRequest
+}
+
+// s3RetryPolicy 读取S3对象的重试参数
+type s3RetryPolicy struct {
+	Attempts int           // 连续失败的最大重试次数，读到新数据后重新计数
+	Backoff  time.Duration // 首次重试前的等待时间，之后每次翻倍，上限 maxS3RetryBackoff
+}
+
+// s3RetryPolicyFor 从 settings.s3_import 读取重试参数，未配置时使用默认值
+func s3RetryPolicyFor(config *Config) s3RetryPolicy {
+	policy := s3RetryPolicy{
+		Attempts: defaultS3RetryAttempts,
+		Backoff:  defaultS3RetryBackoffMs * time.Millisecond,
+	}
+	if config.Settings.S3Import.RetryAttempts > 0 {
+		policy.Attempts = config.Settings.S3Import.RetryAttempts
+	}
+	if config.Settings.S3Import.RetryBackoffMs > 0 {
+		policy.Backoff = time.Duration(config.Settings.S3Import.RetryBackoffMs) * time.Millisecond
+	}
+	return policy
+}
+
+// wait 第 failures 次连续失败后的等待时间
+func (p s3RetryPolicy) wait(failures int) time.Duration {
+	d := p.Backoff
+	for i := 1; i < failures && d < maxS3RetryBackoff; i++ {
+		d *= 2
+	}
+	if d > maxS3RetryBackoff {
+		d = maxS3RetryBackoff
+	}
+	return d
+}
+
+// s3ObjectGetter 读取对象用到的S3接口，由 *s3.Client 实现
+type s3ObjectGetter interface {
+	GetObject(ctx context.Context, params *s3.GetObjectInput, optFns ...func(*s3.Options)) (*s3.GetObjectOutput, error)
+}
+
+// s3RetryReader 可续传的S3对象读取器
+// 读取响应体遇到暂时性错误时关闭连接，等待后以 Range: bytes=<已读字节>- 重新请求，对调用方表现为一个连续的流；
+// 每次请求（包括首次）都带 If-Match 校验列出对象时的 ETag，对象在列出后或导入期间被替换时返回 412，
+// 不会读到与清单校验和不符的内容，也不会拼接出新旧混合的数据
+type s3RetryReader struct {
+	client  s3ObjectGetter
+	bucket  string
+	key     string
+	etag    string
+	size    int64 // 对象大小，读到 EOF 但字节数不足时视为连接被截断
+	policy  s3RetryPolicy
+	body    io.ReadCloser
+	offset  int64
+	fails   int          // 连续失败次数
+	resumes int          // 续传次数
+	err     *s3FileError // 最终失败的错误，设置后不再重试
+}
+
+// OpenObjectReader 打开可续传的S3对象读取器，首次请求失败同样按重试策略重试
+func (s3c *S3Client) OpenObjectReader(file S3FileInfo, policy s3RetryPolicy) (*s3RetryReader, error) {
+	return openS3RetryReader(s3c.client, s3c.bucket, file, policy)
+}
+
+// openS3RetryReader 通过 client 打开可续传的对象读取器
+func openS3RetryReader(client s3ObjectGetter, bucket string, file S3FileInfo, policy s3RetryPolicy) (*s3RetryReader, error) {
+	r := &s3RetryReader{client: client, bucket: bucket, key: file.Key, etag: file.ETag, size: file.Size, policy: policy}
+	if err := r.connect(); err != nil {
+		return nil, err
+	}
+	return r, nil
+}
+
+// connect 从当前偏移量请求对象，可重试的错误按策略等待后重试
+func (r *s3RetryReader) connect() error {
+	for {
+		input := &s3.GetObjectInput{
+			Bucket: aws.String(r.bucket),
+			Key:    aws.String(r.key),
+		}
+		if r.etag != "" {
+			input.IfMatch = aws.String(`"` + r.etag + `"`)
+		}
+		if r.offset > 0 {
+			input.Range = aws.String(fmt.Sprintf("bytes=%d-", r.offset))
+		}
+		result, err := r.client.GetObject(context.TODO(), input)
+		if err == nil {
+			r.body = result.Body
+			return nil
+		}
+		if err := r.retryOrFail("获取S3对象流失败", err); err != nil {
+			return err
+		}
+	}
+}
+
+// retryOrFail 记录一次失败：可重试且未用尽次数时等待后返回 nil，否则返回带分类的最终错误
+func (r *s3RetryReader) retryOrFail(what string, err error) error {
+	class := classifyS3Error(err)
+	r.fails++
+	if class != s3ErrTransient || r.fails > r.policy.Attempts {
+		switch class {
+		case s3ErrTransient:
+			err = fmt.Errorf("%s（已重试 %d 次）: %v", what, r.policy.Attempts, err)
+		case s3ErrModified:
+			err = fmt.Errorf("%s: 对象 ETag 已不是列出时的 %s，请重新运行导入以重新列出对象: %v", what, r.etag, err)
+		default:
+			err = fmt.Errorf("%s: %v", what, err)
+		}
+		r.err = &s3FileError{Class: class, Err: err}
+		return r.err
+	}
+	wait := r.policy.wait(r.fails)
+	fmt.Printf("  🔁 %s 在第 %d 字节处%s: %v，%v 后重试 (%d/%d)\n", r.key, r.offset, what, err, wait, r.fails, r.policy.Attempts)
+	time.Sleep(wait)
+	return nil
+}
+
+// Read 读取对象数据，连接中断时从已读位置续传
+func (r *s3RetryReader) Read(p []byte) (int, error) {
+	for {
+		if r.err != nil {
+			return 0, r.err
+		}
+		if r.body == nil {
+			if err := r.connect(); err != nil {
+				return 0, err
+			}
+			r.resumes++
+		}
+		n, err := r.body.Read(p)
+		r.offset += int64(n)
+		if n > 0 {
+			r.fails = 0
+		}
+		if err == io.EOF && r.size > 0 && r.offset < r.size {
+			err = fmt.Errorf("连接在 %d/%d 字节处被截断: %w", r.offset, r.size, io.ErrUnexpectedEOF)
+		}
+		if err == nil || err == io.EOF {
+			return n, err
+		}
+		r.body.Close()
+		r.body = nil
+		if err := r.retryOrFail("读取S3对象失败", err); err != nil {
+			return n, err
+		}
+		if n > 0 {
+			return n, nil
+		}
+	}
+}
+
+// Close 关闭当前连接
+func (r *s3RetryReader) Close() error {
+	if r.body == nil {
+		return nil
+	}
+	err := r.body.Close()
+	r.body = nil
+	return err
+}
@@ -0,0 +1,256 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"strings"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	awshttp "github.com/aws/aws-sdk-go-v2/aws/transport/http"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	"github.com/aws/smithy-go"
+	smithyhttp "github.com/aws/smithy-go/transport/http"
+)
+
+// s3ResponseError 按 SDK 的包装方式构造带 HTTP 状态码的错误，code 非空时内层为带错误码的 API 错误
+func s3ResponseError(status int, code string) error {
+	var inner error = fmt.Errorf("http response error StatusCode: %d", status)
+	if code != "" {
+		inner = &smithy.GenericAPIError{Code: code, Message: code}
+	}
+	return &smithy.OperationError{
+		ServiceID:     "S3",
+		OperationName: "GetObject",
+		Err: &awshttp.ResponseError{
+			ResponseError: &smithyhttp.ResponseError{
+				Response: &smithyhttp.Response{Response: &http.Response{StatusCode: status}},
+				Err:      inner,
+			},
+		},
+	}
+}
+
+func TestClassifyS3Error(t *testing.T) {
+	tests := []struct {
+		name string
+		err  error
+		want string
+	}{
+		{"取消", fmt.Errorf("读取: %w", context.Canceled), s3ErrCanceled},
+		{"NoSuchKey", s3ResponseError(404, "NoSuchKey"), s3ErrNotFound},
+		{"AccessDenied", s3ResponseError(403, "AccessDenied"), s3ErrDenied},
+		{"PreconditionFailed", s3ResponseError(412, "PreconditionFailed"), s3ErrModified},
+		{"SlowDown", s3ResponseError(503, "SlowDown"), s3ErrTransient},
+		{"未知错误码按状态码分类", s3ResponseError(500, "SomethingElse"), s3ErrTransient},
+		{"未知错误码的客户端错误", s3ResponseError(400, "InvalidArgument"), s3ErrRequest},
+		{"仅状态码 404", s3ResponseError(404, ""), s3ErrNotFound},
+		{"仅状态码 401", s3ResponseError(401, ""), s3ErrDenied},
+		{"仅状态码 412", s3ResponseError(412, ""), s3ErrModified},
+		{"仅状态码 429", s3ResponseError(429, ""), s3ErrTransient},
+		{"仅状态码 408", s3ResponseError(408, ""), s3ErrTransient},
+		{"仅状态码 416", s3ResponseError(416, ""), s3ErrRequest},
+		{"不带错误码的 API 错误", &smithy.GenericAPIError{Code: "Whatever"}, s3ErrRequest},
+		{"连接错误", &smithy.OperationError{Err: &smithyhttp.RequestSendError{Err: errors.New("dial tcp: connection refused")}}, s3ErrTransient},
+		{"响应体截断", fmt.Errorf("读取: %w", io.ErrUnexpectedEOF), s3ErrTransient},
+		{"连接重置", fmt.Errorf("读取: %w", syscall.ECONNRESET), s3ErrTransient},
+		{"管道断开", fmt.Errorf("读取: %w", syscall.EPIPE), s3ErrTransient},
+		{"超时", context.DeadlineExceeded, s3ErrTransient},
+		{"网络错误", &net.OpError{Op: "read", Net: "tcp", Err: errors.New("i/o timeout")}, s3ErrTransient},
+		{"文本中的连接重置", errors.New("read tcp 10.0.0.1:443: connection reset by peer"), s3ErrTransient},
+		{"其他错误", errors.New("boom"), s3ErrRequest},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := classifyS3Error(tt.err); got != tt.want {
+				t.Fatalf("classifyS3Error(%v) = %q，期望 %q", tt.err, got, tt.want)
+			}
+		})
+	}
+}
+
+// fakeS3Object 内存中的S3对象：按 Range 与 If-Match 响应 GetObject，并记录每次请求
+type fakeS3Object struct {
+	data   []byte
+	etag   string
+	breaks []fakeBreak // 依次作用于每次连接的中断，用完后连接正常读到结尾
+	getErr []error     // 依次作为每次请求的错误，用完后正常响应
+	inputs []*s3.GetObjectInput
+}
+
+// fakeBreak 连接读到 at 字节（对象内的绝对偏移量）后返回 err
+type fakeBreak struct {
+	at  int64
+	err error
+}
+
+func (o *fakeS3Object) GetObject(ctx context.Context, in *s3.GetObjectInput, optFns ...func(*s3.Options)) (*s3.GetObjectOutput, error) {
+	o.inputs = append(o.inputs, in)
+	if len(o.getErr) > 0 {
+		err := o.getErr[0]
+		o.getErr = o.getErr[1:]
+		return nil, err
+	}
+	if in.IfMatch != nil && aws.ToString(in.IfMatch) != `"`+o.etag+`"` {
+		return nil, s3ResponseError(412, "PreconditionFailed")
+	}
+	var start int64
+	if in.Range != nil {
+		if _, err := fmt.Sscanf(aws.ToString(in.Range), "bytes=%d-", &start); err != nil {
+			return nil, s3ResponseError(416, "InvalidRange")
+		}
+	}
+	body := &fakeBody{r: bytes.NewReader(o.data[start:]), err: io.EOF}
+	if len(o.breaks) > 0 {
+		b := o.breaks[0]
+		o.breaks = o.breaks[1:]
+		body = &fakeBody{r: bytes.NewReader(o.data[start:b.at]), err: b.err}
+	}
+	return &s3.GetObjectOutput{Body: body}, nil
+}
+
+// fakeBody 读完数据后返回 err 的响应体，err 为 io.EOF 时表示正常结束
+type fakeBody struct {
+	r   *bytes.Reader
+	err error
+}
+
+func (b *fakeBody) Read(p []byte) (int, error) {
+	// 每次最多读 64 字节，中断前会经历多次成功读取
+	if len(p) > 64 {
+		p = p[:64]
+	}
+	n, err := b.r.Read(p)
+	if err == io.EOF {
+		return n, b.err
+	}
+	return n, err
+}
+
+func (b *fakeBody) Close() error { return nil }
+
+func testS3Object(size int) *fakeS3Object {
+	data := make([]byte, size)
+	for i := range data {
+		data[i] = byte('a' + i%26)
+	}
+	return &fakeS3Object{data: data, etag: "etag-1"}
+}
+
+var testS3Policy = s3RetryPolicy{Attempts: 2, Backoff: time.Millisecond}
+
+func TestS3RetryReaderResume(t *testing.T) {
+	t.Run("中途断开后从断点续传", func(t *testing.T) {
+		obj := testS3Object(1000)
+		obj.breaks = []fakeBreak{
+			{at: 300, err: fmt.Errorf("read: %w", syscall.ECONNRESET)},
+			{at: 700, err: io.EOF}, // 字节数不足的 EOF 视为连接被截断
+		}
+		file := S3FileInfo{Key: "93/GameResults_1_1.json", ETag: obj.etag, Size: int64(len(obj.data))}
+		r, err := openS3RetryReader(obj, "bucket", file, testS3Policy)
+		if err != nil {
+			t.Fatalf("打开失败: %v", err)
+		}
+		got, err := io.ReadAll(r)
+		if err != nil {
+			t.Fatalf("读取失败: %v", err)
+		}
+		if !bytes.Equal(got, obj.data) {
+			t.Fatalf("续传拼接的内容与对象不一致（%d/%d 字节）", len(got), len(obj.data))
+		}
+		if r.resumes != 2 {
+			t.Fatalf("续传 %d 次，期望 2 次", r.resumes)
+		}
+		wantRanges := []string{"", "bytes=300-", "bytes=700-"}
+		if len(obj.inputs) != len(wantRanges) {
+			t.Fatalf("请求 %d 次，期望 %d 次", len(obj.inputs), len(wantRanges))
+		}
+		for i, in := range obj.inputs {
+			if got := aws.ToString(in.Range); got != wantRanges[i] {
+				t.Fatalf("第 %d 次请求 Range = %q，期望 %q", i+1, got, wantRanges[i])
+			}
+			if got := aws.ToString(in.IfMatch); got != `"etag-1"` {
+				t.Fatalf("第 %d 次请求 If-Match = %q，期望 \"etag-1\"", i+1, got)
+			}
+		}
+	})
+
+	t.Run("首次请求失败后重试", func(t *testing.T) {
+		obj := testS3Object(200)
+		obj.getErr = []error{s3ResponseError(503, "SlowDown")}
+		r, err := openS3RetryReader(obj, "bucket", S3FileInfo{Key: "k", ETag: obj.etag, Size: 200}, testS3Policy)
+		if err != nil {
+			t.Fatalf("打开失败: %v", err)
+		}
+		got, err := io.ReadAll(r)
+		if err != nil || !bytes.Equal(got, obj.data) {
+			t.Fatalf("读取结果不正确: %d 字节, %v", len(got), err)
+		}
+		if r.resumes != 0 || len(obj.inputs) != 2 {
+			t.Fatalf("续传 %d 次、请求 %d 次，期望 0 次、2 次", r.resumes, len(obj.inputs))
+		}
+	})
+
+	t.Run("列出后对象被替换", func(t *testing.T) {
+		obj := testS3Object(200)
+		obj.etag = "etag-2"
+		_, err := openS3RetryReader(obj, "bucket", S3FileInfo{Key: "k", ETag: "etag-1", Size: 200}, testS3Policy)
+		if importFailureClass(err) != s3ErrModified {
+			t.Fatalf("期望 [%s]，得到 %v", s3ErrModified, err)
+		}
+		if len(obj.inputs) != 1 {
+			t.Fatalf("412 不应重试，实际请求 %d 次", len(obj.inputs))
+		}
+		if !strings.Contains(err.Error(), "重新列出") {
+			t.Fatalf("错误信息应提示重新列出对象: %v", err)
+		}
+	})
+
+	t.Run("续传时对象被替换", func(t *testing.T) {
+		obj := testS3Object(1000)
+		obj.breaks = []fakeBreak{{at: 400, err: fmt.Errorf("read: %w", io.ErrUnexpectedEOF)}}
+		r, err := openS3RetryReader(obj, "bucket", S3FileInfo{Key: "k", ETag: obj.etag, Size: 1000}, testS3Policy)
+		if err != nil {
+			t.Fatalf("打开失败: %v", err)
+		}
+		obj.etag = "etag-2"
+		got, err := io.ReadAll(r)
+		if importFailureClass(err) != s3ErrModified {
+			t.Fatalf("期望 [%s]，得到 %v", s3ErrModified, err)
+		}
+		if len(got) != 400 {
+			t.Fatalf("失败前读到 %d 字节，期望 400", len(got))
+		}
+		if len(obj.inputs) != 2 {
+			t.Fatalf("412 不应重试，实际请求 %d 次", len(obj.inputs))
+		}
+	})
+
+	t.Run("连续失败超过重试次数", func(t *testing.T) {
+		obj := testS3Object(1000)
+		reset := fmt.Errorf("read: %w", syscall.ECONNRESET)
+		obj.breaks = []fakeBreak{{at: 100, err: reset}, {at: 100, err: reset}, {at: 100, err: reset}}
+		r, err := openS3RetryReader(obj, "bucket", S3FileInfo{Key: "k", ETag: obj.etag, Size: 1000}, testS3Policy)
+		if err != nil {
+			t.Fatalf("打开失败: %v", err)
+		}
+		got, err := io.ReadAll(r)
+		if importFailureClass(err) != s3ErrTransient {
+			t.Fatalf("期望 [%s]，得到 %v", s3ErrTransient, err)
+		}
+		if len(got) != 100 {
+			t.Fatalf("失败前读到 %d 字节，期望 100", len(got))
+		}
+		// 连续 3 次失败（超过 2 次重试）后放弃
+		if len(obj.inputs) != 3 {
+			t.Fatalf("请求 %d 次，期望 3 次", len(obj.inputs))
+		}
+	})
+}
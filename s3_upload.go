@@ -0,0 +1,346 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	"github.com/aws/aws-sdk-go-v2/service/s3/types"
+)
+
+// S3上传默认参数
+const (
+	defaultS3UploadConcurrency = 4
+	defaultS3PartSizeMB        = 64
+	minS3PartSizeMB            = 5        // S3 要求除最后一片外每个分片至少 5MB
+	s3MetaSHA256               = "sha256" // 对象元数据 x-amz-meta-sha256，记录上传时本地文件的校验和
+)
+
+// s3UploadTask 单个待上传的本地文件
+type s3UploadTask struct {
+	Path string
+	Key  string
+	Size int64
+}
+
+// s3UploadSummary 上传结果汇总
+type s3UploadSummary struct {
+	mu       sync.Mutex
+	Uploaded int
+	Skipped  int
+	Planned  int // --dry-run 时将上传的文件数
+	Bytes    int64
+	Failed   []string
+}
+
+// collectUploadTasks 收集 <output-dir>/<gameId> 与 <output-dir>/<gameId>_fb 下的结果文件，分别对应S3的 normal 与 fb 前缀
+// 生成清单 manifest.json 单独返回，在结果文件全部上传后再上传
+func collectUploadTasks(s3c *S3Client, gameID int, levelFilter string) ([]s3UploadTask, []s3UploadTask, error) {
+	var files, manifests []s3UploadTask
+	for _, mode := range []string{"normal", "fb"} {
+		dir := gameOutputDir(gameID, mode == "fb")
+		entries, err := os.ReadDir(dir)
+		if os.IsNotExist(err) {
+			continue
+		}
+		if err != nil {
+			return nil, nil, fmt.Errorf("读取目录 %s 失败: %v", dir, err)
+		}
+		prefix := s3c.GamePrefix(gameID, mode)
+		for _, entry := range entries {
+			if entry.IsDir() {
+				continue
+			}
+			name := entry.Name()
+			isManifest := name == manifestFileName
+			if !isManifest && !isResultFileName(name) {
+				continue
+			}
+			if !isManifest {
+				// 只上传 import-s3 能识别的文件名
+				level, _, ok := parseResultFileName(s3c.fileNames, name)
+				if !ok || levelFilter != "" && strconv.Itoa(level) != levelFilter {
+					continue
+				}
+			}
+			info, err := entry.Info()
+			if err != nil {
+				return nil, nil, fmt.Errorf("读取文件 %s 信息失败: %v", name, err)
+			}
+			task := s3UploadTask{Path: filepath.Join(dir, name), Key: prefix + name, Size: info.Size()}
+			if isManifest {
+				manifests = append(manifests, task)
+			} else {
+				files = append(files, task)
+			}
+		}
+	}
+	return files, manifests, nil
+}
+
+// RemoteSHA256 读取对象元数据中记录的 sha256，对象不存在时 exists 为 false
+func (s3c *S3Client) RemoteSHA256(key string) (string, bool, error) {
+	head, err := s3c.client.HeadObject(context.TODO(), &s3.HeadObjectInput{
+		Bucket: aws.String(s3c.bucket),
+		Key:    aws.String(key),
+	})
+	if err != nil {
+		var notFound *types.NotFound
+		if errors.As(err, &notFound) {
+			return "", false, nil
+		}
+		return "", false, fmt.Errorf("查询S3对象失败: %v", err)
+	}
+	return head.Metadata[s3MetaSHA256], true, nil
+}
+
+// UploadFile 上传已打开的本地文件（size 字节）并在元数据中记录 sha256；不超过 partSize 的文件直接 PutObject，否则分片上传
+func (s3c *S3Client) UploadFile(fh *os.File, key string, size int64, checksum string, partSize int64) error {
+	contentType := "application/json"
+	if strings.HasSuffix(strings.ToLower(key), gzipFileExt) {
+		contentType = "application/gzip"
+	}
+	metadata := map[string]string{s3MetaSHA256: checksum}
+
+	if size <= partSize {
+		_, err := s3c.client.PutObject(context.TODO(), &s3.PutObjectInput{
+			Bucket:        aws.String(s3c.bucket),
+			Key:           aws.String(key),
+			Body:          io.NewSectionReader(fh, 0, size),
+			ContentLength: aws.Int64(size),
+			ContentType:   aws.String(contentType),
+			Metadata:      metadata,
+		})
+		if err != nil {
+			return fmt.Errorf("上传S3对象失败: %v", err)
+		}
+		return nil
+	}
+
+	created, err := s3c.client.CreateMultipartUpload(context.TODO(), &s3.CreateMultipartUploadInput{
+		Bucket:      aws.String(s3c.bucket),
+		Key:         aws.String(key),
+		ContentType: aws.String(contentType),
+		Metadata:    metadata,
+	})
+	if err != nil {
+		return fmt.Errorf("创建分片上传失败: %v", err)
+	}
+	uploadID := created.UploadId
+
+	var parts []types.CompletedPart
+	for offset, number := int64(0), int32(1); offset < size; offset, number = offset+partSize, number+1 {
+		n := partSize
+		if rest := size - offset; rest < n {
+			n = rest
+		}
+		part, err := s3c.client.UploadPart(context.TODO(), &s3.UploadPartInput{
+			Bucket:        aws.String(s3c.bucket),
+			Key:           aws.String(key),
+			UploadId:      uploadID,
+			PartNumber:    aws.Int32(number),
+			Body:          io.NewSectionReader(fh, offset, n),
+			ContentLength: aws.Int64(n),
+		})
+		if err != nil {
+			s3c.abortMultipartUpload(key, uploadID)
+			return fmt.Errorf("上传第 %d 个分片失败: %v", number, err)
+		}
+		parts = append(parts, types.CompletedPart{ETag: part.ETag, PartNumber: aws.Int32(number)})
+	}
+
+	_, err = s3c.client.CompleteMultipartUpload(context.TODO(), &s3.CompleteMultipartUploadInput{
+		Bucket:          aws.String(s3c.bucket),
+		Key:             aws.String(key),
+		UploadId:        uploadID,
+		MultipartUpload: &types.CompletedMultipartUpload{Parts: parts},
+	})
+	if err != nil {
+		s3c.abortMultipartUpload(key, uploadID)
+		return fmt.Errorf("完成分片上传失败: %v", err)
+	}
+	return nil
+}
+
+// abortMultipartUpload 放弃未完成的分片上传，避免残留分片持续计费
+func (s3c *S3Client) abortMultipartUpload(key string, uploadID *string) {
+	_, err := s3c.client.AbortMultipartUpload(context.TODO(), &s3.AbortMultipartUploadInput{
+		Bucket:   aws.String(s3c.bucket),
+		Key:      aws.String(key),
+		UploadId: uploadID,
+	})
+	if err != nil {
+		fmt.Printf("⚠️ 放弃分片上传失败 %s: %v\n", key, err)
+	}
+}
+
+// uploadTasks 并发上传一组文件：本地 sha256 与对象元数据一致时跳过（--force 时总是上传）
+func uploadTasks(s3c *S3Client, tasks []s3UploadTask, concurrency int, partSize int64, force, dryRun bool, summary *s3UploadSummary) {
+	sem := make(chan struct{}, concurrency)
+	var wg sync.WaitGroup
+	for i, task := range tasks {
+		sem <- struct{}{}
+		wg.Add(1)
+		go func(index int, task s3UploadTask) {
+			defer func() { <-sem; wg.Done() }()
+			progress := fmt.Sprintf("[%d/%d]", index+1, len(tasks))
+			fail := func(err error) {
+				fmt.Printf("❌ %s %s: %v\n", progress, task.Path, err)
+				summary.mu.Lock()
+				summary.Failed = append(summary.Failed, fmt.Sprintf("%s: %v", task.Path, err))
+				summary.mu.Unlock()
+			}
+
+			// 校验和与上传使用同一个打开的文件，上传期间重新生成（原子重命名）不会导致两者不一致
+			fh, err := os.Open(task.Path)
+			if err != nil {
+				fail(err)
+				return
+			}
+			defer fh.Close()
+			checksum, err := readerSHA256(fh)
+			if err != nil {
+				fail(err)
+				return
+			}
+			st, err := fh.Stat()
+			if err != nil {
+				fail(err)
+				return
+			}
+			task.Size = st.Size()
+			if !force {
+				remote, exists, err := s3c.RemoteSHA256(task.Key)
+				if err != nil {
+					fail(err)
+					return
+				}
+				if exists && remote == checksum {
+					fmt.Printf("⏭️  %s %s 未变化，跳过\n", progress, task.Key)
+					summary.mu.Lock()
+					summary.Skipped++
+					summary.mu.Unlock()
+					return
+				}
+			}
+			if dryRun {
+				fmt.Printf("🧪 [dry-run] %s 将上传 %s → %s (%.2fMB)\n", progress, task.Path, task.Key, float64(task.Size)/(1024*1024))
+				summary.mu.Lock()
+				summary.Planned++
+				summary.Bytes += task.Size
+				summary.mu.Unlock()
+				return
+			}
+
+			start := time.Now()
+			if err := s3c.UploadFile(fh, task.Key, task.Size, checksum, partSize); err != nil {
+				fail(err)
+				return
+			}
+			fmt.Printf("✅ %s %s → %s (%.2fMB, 耗时: %v)\n", progress, task.Path, task.Key, float64(task.Size)/(1024*1024), time.Since(start))
+			summary.mu.Lock()
+			summary.Uploaded++
+			summary.Bytes += task.Size
+			summary.mu.Unlock()
+		}(i, task)
+	}
+	wg.Wait()
+}
+
+// runS3UploadMode 上传生成目录到S3：output/<gameId> → <normal 前缀>，output/<gameId>_fb → <fb 前缀>，即 import-s3 读取的位置
+func runS3UploadMode(gameIds []int, levelFilter string, force, dryRun bool) {
+	startTime := time.Now()
+
+	config, err := LoadConfig(configPath)
+	if err != nil {
+		log.Fatalf("❌ 加载配置失败: %v", err)
+	}
+	if !config.S3.Enabled {
+		log.Fatalf("❌ S3功能未启用，请在配置文件中设置 s3.enabled: true")
+	}
+	s3Client, err := NewS3Client(config)
+	if err != nil {
+		log.Fatalf("❌ 创建S3客户端失败: %v", err)
+	}
+
+	concurrency := config.Settings.S3Upload.MaxConcurrency
+	if concurrency <= 0 {
+		concurrency = defaultS3UploadConcurrency
+	}
+	partSizeMB := config.Settings.S3Upload.PartSizeMB
+	if partSizeMB <= 0 {
+		partSizeMB = defaultS3PartSizeMB
+	}
+	partSize := int64(partSizeMB) << 20
+
+	var files, manifests []s3UploadTask
+	var totalBytes int64
+	for _, gameID := range gameIds {
+		gameFiles, gameManifests, err := collectUploadTasks(s3Client, gameID, levelFilter)
+		if err != nil {
+			log.Fatalf("❌ %v", err)
+		}
+		if len(gameFiles) == 0 {
+			fmt.Printf("⚠️ 游戏 %d 在 %s 下没有可上传的结果文件\n", gameID, outputRoot)
+			continue
+		}
+		for _, f := range gameFiles {
+			totalBytes += f.Size
+		}
+		fmt.Printf("📁 游戏 %d: %d 个结果文件（normal → s3://%s/%s，fb → s3://%s/%s）\n",
+			gameID, len(gameFiles), s3Client.bucket, s3Client.GamePrefix(gameID, "normal"), s3Client.bucket, s3Client.GamePrefix(gameID, "fb"))
+		files = append(files, gameFiles...)
+		manifests = append(manifests, gameManifests...)
+	}
+	if len(files) == 0 {
+		log.Fatalf("❌ 没有可上传的文件")
+	}
+	fmt.Printf("📤 共 %d 个结果文件 (%.2fMB)，%d 个生成清单，并发 %d，分片大小 %dMB\n",
+		len(files), float64(totalBytes)/(1024*1024), len(manifests), concurrency, partSizeMB)
+
+	summary := &s3UploadSummary{}
+	uploadTasks(s3Client, files, concurrency, partSize, force, dryRun, summary)
+	// 生成清单描述整个目录，结果文件全部上传成功后再上传，避免清单先于文件出现在S3中
+	if len(manifests) > 0 {
+		if len(summary.Failed) > 0 {
+			fmt.Printf("⚠️ 有文件上传失败，跳过 %d 个生成清单\n", len(manifests))
+		} else {
+			uploadTasks(s3Client, manifests, concurrency, partSize, force, dryRun, summary)
+		}
+	}
+
+	duration := time.Since(startTime)
+	fmt.Printf("\n📊 S3上传汇总:\n")
+	if dryRun {
+		fmt.Printf("  - 将上传: %d 个文件 (%.2fMB)\n", summary.Planned, float64(summary.Bytes)/(1024*1024))
+	} else {
+		fmt.Printf("  - 已上传: %d 个文件 (%.2fMB)\n", summary.Uploaded, float64(summary.Bytes)/(1024*1024))
+	}
+	fmt.Printf("  - 未变化跳过: %d 个文件\n", summary.Skipped)
+	fmt.Printf("  - 失败: %d 个文件\n", len(summary.Failed))
+	fmt.Printf("  - 总耗时: %v\n", duration)
+	if !dryRun && duration.Seconds() > 0 {
+		fmt.Printf("  - 平均速度: %.2fMB/s\n", float64(summary.Bytes)/(1024*1024)/duration.Seconds())
+	}
+	if len(summary.Failed) > 0 {
+		for _, f := range summary.Failed {
+			fmt.Printf("     - %s\n", f)
+		}
+		os.Exit(1)
+	}
+	if dryRun {
+		fmt.Println("🧪 [dry-run] 未上传任何文件")
+		return
+	}
+	fmt.Printf("🎉 S3上传完成！\n")
+}
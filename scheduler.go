@@ -0,0 +1,277 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"sync"
+	"time"
+)
+
+// generationTask 单个生成任务：某游戏某档位的第几次生成
+type generationTask struct {
+	level      RtpLevel
+	testNumber int
+}
+
+// generationGame 调度器中单个游戏的状态
+// 候选数据池在该游戏的第一个任务开始时加载，最后一个任务结束后释放，避免同时持有所有游戏的数据
+type generationGame struct {
+	config   *Config
+	db       *Database // 绑定到该游戏配置的数据库视图，源表按该游戏的ID读取
+	strategy SelectionStrategy
+	index    int
+	limit    int // 该游戏同时运行的任务数上限
+	tasks    []generationTask
+	next     int // 下一个待派发的任务
+	running  int
+	finished int
+	start    time.Time
+
+	levelRemaining map[float64]int
+	levelStart     map[float64]time.Time
+
+	poolsOnce sync.Once
+	pools     *CandidatePools
+	poolsErr  error
+
+	failedLevels []float64
+	failedTests  []string
+
+	run   ManifestRun    // 写入生成清单的运行信息
+	files []ManifestFile // 本次运行成功生成的文件
+}
+
+// generationScheduler 所有游戏共用的任务调度器：全局并发数与单游戏并发数共同限制同时运行的任务
+// 任务按游戏、档位顺序派发，某个游戏达到上限或已派发完时由后续游戏的任务补上空闲的工作槽，不再逐档位等待
+type generationScheduler struct {
+	db       *Database
+	baseSeed int64
+	workers  int
+	games    []*generationGame
+
+	mu     sync.Mutex
+	cond   *sync.Cond
+	pingMu sync.Mutex
+}
+
+// newGenerationScheduler 创建调度器，全局并发数取 settings.generation.max_concurrency，未配置时为 CPU 核数
+func newGenerationScheduler(db *Database, config *Config, baseSeed int64) *generationScheduler {
+	workers := config.Settings.Generation.MaxConcurrency
+	if workers <= 0 {
+		workers = runtime.NumCPU()
+	}
+	s := &generationScheduler{db: db, baseSeed: baseSeed, workers: workers}
+	s.cond = sync.NewCond(&s.mu)
+	return s
+}
+
+// gameConcurrency 单个游戏的并发上限：multi_game.games[i].concurrency > settings.generation.per_game_concurrency > 全局并发数
+func gameConcurrency(config *Config, workers int) int {
+	limit := config.Settings.Generation.PerGameConcurrency
+	if game, ok := config.FindGame(config.Game.ID); ok && game.Concurrency > 0 {
+		limit = game.Concurrency
+	}
+	if limit <= 0 || limit > workers {
+		limit = workers
+	}
+	return limit
+}
+
+// AddGame 登记一个游戏的全部 (档位, 第几次) 任务
+func (s *generationScheduler) AddGame(strategy SelectionStrategy, config *Config) {
+	shape := strategy.Shape(config)
+	g := &generationGame{
+		config:         config,
+		db:             s.db.ForConfig(config),
+		strategy:       strategy,
+		index:          len(s.games),
+		limit:          gameConcurrency(config, s.workers),
+		levelRemaining: make(map[float64]int),
+		levelStart:     make(map[float64]time.Time),
+	}
+	for _, level := range strategy.Levels(config) {
+		for t := 1; t <= shape.TestNum; t++ {
+			g.tasks = append(g.tasks, generationTask{level: level, testNumber: t})
+		}
+		g.levelRemaining[level.RtpNo] += shape.TestNum
+	}
+	s.games = append(s.games, g)
+}
+
+// nextGame 按登记顺序返回第一个仍有待派发任务且未达到并发上限的游戏，调用方需持有 s.mu
+func (s *generationScheduler) nextGame() *generationGame {
+	for _, g := range s.games {
+		if g.next < len(g.tasks) && g.running < g.limit {
+			return g
+		}
+	}
+	return nil
+}
+
+// Run 执行全部任务并等待完成，返回加载候选数据池失败的游戏错误
+func (s *generationScheduler) Run() []error {
+	total := 0
+	for _, g := range s.games {
+		total += len(g.tasks)
+	}
+	fmt.Printf("🧵 任务调度: %d 个游戏, 共 %d 个任务, 全局并发 %d\n", len(s.games), total, s.workers)
+
+	var wg sync.WaitGroup
+	running := 0
+	for dispatched := 0; dispatched < total; dispatched++ {
+		s.mu.Lock()
+		var g *generationGame
+		for {
+			if running < s.workers {
+				if g = s.nextGame(); g != nil {
+					break
+				}
+			}
+			s.cond.Wait()
+		}
+		task := g.tasks[g.next]
+		g.next++
+		g.running++
+		running++
+		if g.next == 1 {
+			s.startGame(g)
+		}
+		if _, ok := g.levelStart[task.level.RtpNo]; !ok {
+			g.levelStart[task.level.RtpNo] = time.Now()
+		}
+		s.mu.Unlock()
+
+		wg.Add(1)
+		go func(g *generationGame, task generationTask) {
+			defer wg.Done()
+			s.runTask(g, task)
+
+			s.mu.Lock()
+			g.running--
+			running--
+			s.finishTask(g, task)
+			s.cond.Broadcast()
+			s.mu.Unlock()
+		}(g, task)
+	}
+	wg.Wait()
+
+	var errs []error
+	for _, g := range s.games {
+		if g.poolsErr != nil {
+			errs = append(errs, fmt.Errorf("游戏 %d 加载候选数据失败: %v", g.config.Game.ID, g.poolsErr))
+		}
+	}
+	return errs
+}
+
+// startGame 游戏的第一个任务派发时输出游戏信息，调用方需持有 s.mu
+func (s *generationScheduler) startGame(g *generationGame) {
+	g.start = time.Now()
+	name := g.strategy.Name()
+	g.run = newManifestRun(g.config, name, s.baseSeed, g.start)
+	shape := g.strategy.Shape(g.config)
+	fmt.Printf("\n🎯 开始处理游戏 %d/%d: ID=%d, BL=%.0f, 并发上限 %d\n",
+		g.index+1, len(s.games), g.config.Game.ID, g.config.Bet.BL, g.limit)
+	fmt.Printf("配置加载成功 [%s] - 游戏ID: %d, 目标数据量: %d, 每档位文件数: %d\n", name, g.config.Game.ID, shape.DataNum, shape.TestNum)
+	fmt.Printf("🔧 [%s] %s\n", name, g.strategy.Description())
+}
+
+// finishTask 任务结束后的统计：档位全部完成时输出档位耗时，游戏全部完成时输出失败统计并释放候选数据池，调用方需持有 s.mu
+func (s *generationScheduler) finishTask(g *generationGame, task generationTask) {
+	name := g.strategy.Name()
+	rtpNo := task.level.RtpNo
+	g.levelRemaining[rtpNo]--
+	if g.levelRemaining[rtpNo] == 0 {
+		fmt.Printf("⏱️  [%s] 游戏%d | RTP等级 %.0f 总耗时: %v\n", name, g.config.Game.ID, rtpNo, time.Since(g.levelStart[rtpNo]))
+	}
+
+	g.finished++
+	if g.finished < len(g.tasks) {
+		return
+	}
+	g.pools = nil
+	if g.poolsErr != nil {
+		fmt.Printf("❌ 游戏 %d 生成失败: %v\n", g.config.Game.ID, g.poolsErr)
+		return
+	}
+	printFailureSummary(name, g.config.Game.ID, g.failedLevels, g.failedTests)
+	s.writeManifest(g)
+	fmt.Printf("✅ 游戏 %d 生成完成，耗时: %v\n", g.config.Game.ID, time.Since(g.start))
+}
+
+// writeManifest 将游戏本次成功生成的文件合并写入输出目录的 manifest.json，调用方需持有 s.mu
+func (s *generationScheduler) writeManifest(g *generationGame) {
+	if len(g.files) == 0 {
+		return
+	}
+	g.run.FinishedAt = time.Now().Format(time.RFC3339)
+	dir := gameOutputDir(g.config.Game.ID, g.strategy.Shape(g.config).IsFb)
+	if err := updateGenerationManifest(dir, g.config.Game.ID, g.run, g.files); err != nil {
+		fmt.Printf("⚠️ 游戏 %d 写入生成清单失败: %v\n", g.config.Game.ID, err)
+		return
+	}
+	fmt.Printf("🧾 生成清单已更新: %s (本次 %d 个文件)\n", filepath.Join(dir, manifestFileName), len(g.files))
+}
+
+// loadPools 加载游戏的候选数据池，同一游戏只加载一次
+func (s *generationScheduler) loadPools(g *generationGame) (*CandidatePools, error) {
+	g.poolsOnce.Do(func() {
+		s.pingMu.Lock()
+		if err := s.db.EnsureConnection(); err != nil {
+			fmt.Printf("⚠️ 连接健康检查失败: %v\n", err)
+		}
+		s.pingMu.Unlock()
+		g.pools, g.poolsErr = g.strategy.LoadPools(g.db)
+		if g.poolsErr != nil {
+			return
+		}
+		g.run.SourceTable = strings.Trim(g.db.GetTableName(), `"`)
+		g.run.PoolRows = ManifestPoolRows{Win: len(g.pools.Win), Profit: len(g.pools.Profit), NoWin: len(g.pools.NoWin)}
+		if count, err := g.db.CountSourceRows(); err != nil {
+			fmt.Printf("⚠️ %v\n", err)
+		} else {
+			g.run.SourceRows = count
+		}
+	})
+	return g.pools, g.poolsErr
+}
+
+// runTask 执行单个任务，失败时记录到所属游戏的失败统计，成功时记录生成清单条目
+func (s *generationScheduler) runTask(g *generationGame, task generationTask) {
+	pools, err := s.loadPools(g)
+	if err != nil {
+		return
+	}
+
+	config := g.config
+	name := g.strategy.Name()
+	level, testIndex := task.level, task.testNumber
+	totalBet := g.strategy.Shape(config).TotalBet()
+	testStartTime := time.Now()
+	fmt.Printf("▶️ [%s] 开始生成 | 游戏%d | RTP等级 %.0f | 第%d次 | %s\n",
+		name, config.Game.ID, level.RtpNo, testIndex, testStartTime.Format(time.RFC3339))
+	fmt.Printf("🔧 [%s] totalBet=%.2f allowWin_base=%.2f\n", name, totalBet, totalBet.MulRatio(level.Rtp))
+
+	seed := taskSeed(s.baseSeed, config.Game.ID, level.RtpNo, testIndex)
+	outputDir := gameOutputDir(config.Game.ID, g.strategy.Shape(config).IsFb)
+	entry, err := runStrategyTask(config, g.strategy, pools, level, testIndex, seed, outputDir)
+	if err != nil {
+		log.Printf("[%s] RTP测试失败: %v", name, err)
+	}
+	// 记录失败的档位和测试或生成清单条目（线程安全）
+	s.mu.Lock()
+	if err != nil {
+		g.failedLevels = append(g.failedLevels, level.RtpNo)
+		g.failedTests = append(g.failedTests, fmt.Sprintf("RTP%.0f_第%d次", level.RtpNo, testIndex))
+	} else {
+		g.files = append(g.files, *entry)
+	}
+	s.mu.Unlock()
+
+	fmt.Printf("⏱️  [%s] 游戏%d | RTP等级 %.0f (第%d次生成) 耗时: %v\n",
+		name, config.Game.ID, level.RtpNo, testIndex, time.Since(testStartTime))
+}
@@ -0,0 +1,284 @@
+package main
+
+import (
+	"database/sql"
+	"database/sql/driver"
+	"fmt"
+	"io"
+	"math/rand"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// fakeSource 测试用的内存源表：按表名保存源数据，并记录每张表收到的查询
+type fakeSource struct {
+	mu      sync.Mutex
+	tables  map[string][]GameResultData
+	queries map[string][]string
+}
+
+var (
+	fakeSourcesMu sync.Mutex
+	fakeSources   = make(map[string]*fakeSource)
+	fakeSourceSeq int
+)
+
+func init() {
+	sql.Register("fakesource", fakeSourceDriver{})
+}
+
+// fakeTableRegexp 从查询中取出源表名
+var fakeTableRegexp = regexp.MustCompile(`FROM "([^"]+)"`)
+
+// fakeSourceFilters 源数据查询的 WHERE 条件（空白归一化后）与对应的过滤条件
+var fakeSourceFilters = map[string]func(GameResultData) bool{
+	"aw > 0 AND aw < tb * 100 And fb !=2": func(r GameResultData) bool {
+		return r.AW > 0 && r.AW < r.TB*100 && r.FB != 2
+	},
+	"aw > 0 AND aw > tb AND fb != 2": func(r GameResultData) bool {
+		return r.AW > 0 && r.AW > r.TB && r.FB != 2
+	},
+	"aw > 0 AND aw <= tb AND gwt <= 1 AND fb = 2 AND sp = true": func(r GameResultData) bool {
+		return r.AW > 0 && r.AW <= r.TB && r.GWT <= 1 && r.FB == 2 && r.SP
+	},
+	"aw > 0 AND aw > tb AND gwt <= 1 AND fb = 2 AND sp = true": func(r GameResultData) bool {
+		return r.AW > 0 && r.AW > r.TB && r.GWT <= 1 && r.FB == 2 && r.SP
+	},
+	"aw = 0 And sp != true And fb !=2": func(r GameResultData) bool {
+		return r.AW == 0 && !r.SP && r.FB != 2
+	},
+	"aw = 0 AND sp = true AND fb = 2": func(r GameResultData) bool {
+		return r.AW == 0 && r.SP && r.FB == 2
+	},
+}
+
+// newFakeDatabase 使用内存源表创建数据库，tables 以不带引号的表名为键
+func newFakeDatabase(t *testing.T, config *Config, tables map[string][]GameResultData) (*Database, *fakeSource) {
+	t.Helper()
+	src := &fakeSource{tables: tables, queries: make(map[string][]string)}
+	fakeSourcesMu.Lock()
+	fakeSourceSeq++
+	dsn := fmt.Sprintf("source-%d", fakeSourceSeq)
+	fakeSources[dsn] = src
+	fakeSourcesMu.Unlock()
+
+	db, err := sql.Open("fakesource", dsn)
+	if err != nil {
+		t.Fatalf("打开内存源表失败: %v", err)
+	}
+	t.Cleanup(func() { db.Close() })
+	return &Database{DB: db, Config: config, pingInterval: time.Minute}, src
+}
+
+// tableQueries 某张表收到的查询数
+func (s *fakeSource) tableQueries(table string) int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return len(s.queries[table])
+}
+
+func (s *fakeSource) query(query string) (driver.Rows, error) {
+	m := fakeTableRegexp.FindStringSubmatch(query)
+	if m == nil {
+		return nil, fmt.Errorf("内存源表不支持的查询: %s", query)
+	}
+	table := m[1]
+	s.mu.Lock()
+	s.queries[table] = append(s.queries[table], query)
+	rows, ok := s.tables[table]
+	s.mu.Unlock()
+	if !ok {
+		return nil, fmt.Errorf("relation %q does not exist", table)
+	}
+
+	if strings.Contains(query, "count(1)") {
+		return &fakeRows{columns: []string{"count"}, values: [][]driver.Value{{int64(len(rows))}}}, nil
+	}
+	normalized := strings.Join(strings.Fields(query), " ")
+	for where, keep := range fakeSourceFilters {
+		if !strings.Contains(normalized, "WHERE "+where+" ORDER BY id") {
+			continue
+		}
+		result := &fakeRows{columns: []string{"id", "tb", "aw", "gwt", "sp", "fb", "gd", "createdAt", "updatedAt"}}
+		for _, r := range rows {
+			if keep(r) {
+				result.values = append(result.values, []driver.Value{
+					int64(r.ID), []byte(r.TB.String()), []byte(r.AW.String()), int64(r.GWT),
+					r.SP, int64(r.FB), []byte(fmt.Sprintf(`{"id":%d}`, r.ID)), r.CreatedAt, r.UpdatedAt,
+				})
+			}
+		}
+		return result, nil
+	}
+	return nil, fmt.Errorf("内存源表不支持的查询: %s", normalized)
+}
+
+type fakeSourceDriver struct{}
+
+func (fakeSourceDriver) Open(dsn string) (driver.Conn, error) {
+	fakeSourcesMu.Lock()
+	defer fakeSourcesMu.Unlock()
+	src, ok := fakeSources[dsn]
+	if !ok {
+		return nil, fmt.Errorf("未知的内存源表 %s", dsn)
+	}
+	return &fakeConn{src: src}, nil
+}
+
+type fakeConn struct{ src *fakeSource }
+
+func (c *fakeConn) Prepare(query string) (driver.Stmt, error) {
+	return &fakeStmt{src: c.src, query: query}, nil
+}
+func (c *fakeConn) Close() error              { return nil }
+func (c *fakeConn) Begin() (driver.Tx, error) { return nil, fmt.Errorf("内存源表不支持事务") }
+
+type fakeStmt struct {
+	src   *fakeSource
+	query string
+}
+
+func (s *fakeStmt) Close() error  { return nil }
+func (s *fakeStmt) NumInput() int { return -1 }
+func (s *fakeStmt) Exec(args []driver.Value) (driver.Result, error) {
+	return nil, fmt.Errorf("内存源表只读")
+}
+func (s *fakeStmt) Query(args []driver.Value) (driver.Rows, error) {
+	return s.src.query(s.query)
+}
+
+type fakeRows struct {
+	columns []string
+	values  [][]driver.Value
+	next    int
+}
+
+func (r *fakeRows) Columns() []string { return r.columns }
+func (r *fakeRows) Close() error      { return nil }
+func (r *fakeRows) Next(dest []driver.Value) error {
+	if r.next >= len(r.values) {
+		return io.EOF
+	}
+	copy(dest, r.values[r.next])
+	r.next++
+	return nil
+}
+
+// fakeSourceRows 按种子生成源数据：每注 1.00，winRows 条小额中奖与 noWinRows 条不中奖
+func fakeSourceRows(seed int64, winRows, noWinRows int) []GameResultData {
+	rng := rand.New(rand.NewSource(seed))
+	created := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	rows := make([]GameResultData, 0, winRows+noWinRows)
+	for i := 0; i < winRows+noWinRows; i++ {
+		row := GameResultData{ID: len(rows) + 1, TB: 100, GWT: 0, CreatedAt: created, UpdatedAt: created}
+		if i < winRows {
+			row.AW = Money(10 + rng.Intn(290))
+			row.GWT = 1
+		}
+		rows = append(rows, row)
+	}
+	return rows
+}
+
+// testGenerationConfig 每注 1.00、每档 1 个文件的多游戏配置，源表前缀 src_
+func testGenerationConfig(gameIDs ...int) *Config {
+	config := &Config{}
+	config.Tables.SourceTablePrefix = "src_"
+	config.Tables.OutputTablePrefix = "GameResults"
+	config.Tables.DataNum = 100
+	config.Tables.DataTableNum = 1
+	config.Bet.CS, config.Bet.ML, config.Bet.BL = 0.1, 1, 10
+	config.Settings.Timeout = 30
+	config.Settings.Generation.MaxConcurrency = 2
+	config.RtpLevels.Normal = []RtpLevel{{RtpNo: 1, Rtp: 0.6}, {RtpNo: 2, Rtp: 0.9}}
+	config.MultiGame.Enabled = true
+	for _, id := range gameIDs {
+		config.MultiGame.Games = append(config.MultiGame.Games, GameConfig{ID: id, BL: 10})
+	}
+	config.Game.ID = gameIDs[0]
+	return config
+}
+
+// useOutputRoot 将生成结果根目录指向临时目录，测试结束后恢复
+func useOutputRoot(t *testing.T) string {
+	t.Helper()
+	old := outputRoot
+	outputRoot = t.TempDir()
+	t.Cleanup(func() { outputRoot = old })
+	return outputRoot
+}
+
+func TestSchedulerReadsEachGameSourceTable(t *testing.T) {
+	useOutputRoot(t)
+	config := testGenerationConfig(101, 102)
+	db, src := newFakeDatabase(t, config, map[string][]GameResultData{
+		"src_101": fakeSourceRows(1, 300, 200),
+		"src_102": fakeSourceRows(2, 300, 200),
+	})
+
+	strategy, _ := LookupStrategy("generate")
+	scheduler := newGenerationScheduler(db, config, 7)
+	for _, game := range config.MultiGame.Games {
+		scheduler.AddGame(strategy, config.ForGame(game))
+	}
+	if errs := scheduler.Run(); len(errs) > 0 {
+		t.Fatalf("生成失败: %v", errs)
+	}
+
+	for _, table := range []string{"src_101", "src_102"} {
+		if src.tableQueries(table) == 0 {
+			t.Fatalf("源表 %s 未被读取", table)
+		}
+	}
+	for _, g := range scheduler.games {
+		if len(g.failedTests) > 0 {
+			t.Fatalf("游戏 %d 有失败的任务: %v", g.config.Game.ID, g.failedTests)
+		}
+		if len(g.files) != 2 {
+			t.Fatalf("游戏 %d 生成 %d 个文件，期望 2 个", g.config.Game.ID, len(g.files))
+		}
+		for _, f := range g.files {
+			if _, err := os.Stat(filepath.Join(gameOutputDir(g.config.Game.ID, false), f.Name)); err != nil {
+				t.Fatalf("游戏 %d 的文件 %s 未生成: %v", g.config.Game.ID, f.Name, err)
+			}
+		}
+	}
+}
+
+func TestSchedulerManifestRecordsEachGameSource(t *testing.T) {
+	useOutputRoot(t)
+	config := testGenerationConfig(101, 102)
+	db, _ := newFakeDatabase(t, config, map[string][]GameResultData{
+		"src_101": fakeSourceRows(1, 300, 200),
+		"src_102": fakeSourceRows(2, 300, 150),
+	})
+
+	strategy, _ := LookupStrategy("generate")
+	scheduler := newGenerationScheduler(db, config, 7)
+	for _, game := range config.MultiGame.Games {
+		scheduler.AddGame(strategy, config.ForGame(game))
+	}
+	if errs := scheduler.Run(); len(errs) > 0 {
+		t.Fatalf("生成失败: %v", errs)
+	}
+
+	want := map[int]struct {
+		table string
+		rows  int64
+	}{101: {"src_101", 500}, 102: {"src_102", 450}}
+	for gameID, w := range want {
+		manifest, err := readGenerationManifest(gameOutputDir(gameID, false))
+		if err != nil || manifest == nil {
+			t.Fatalf("游戏 %d 读取生成清单失败: %v", gameID, err)
+		}
+		run := manifest.Runs[len(manifest.Runs)-1]
+		if run.SourceTable != w.table || run.SourceRows != w.rows {
+			t.Fatalf("游戏 %d 清单记录源表 %s (%d 行)，期望 %s (%d 行)", gameID, run.SourceTable, run.SourceRows, w.table, w.rows)
+		}
+	}
+}
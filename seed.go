@@ -0,0 +1,38 @@
+package main
+
+import (
+	"fmt"
+	"path/filepath"
+	"strconv"
+	"time"
+)
+
+// resolveBaseSeed 解析 --seed 参数；未指定时使用当前时间并打印，便于事后复现
+func resolveBaseSeed(seedArg string, hasSeed bool) (int64, error) {
+	if hasSeed {
+		seed, err := strconv.ParseInt(seedArg, 10, 64)
+		if err != nil {
+			return 0, fmt.Errorf("无效的种子: %s", seedArg)
+		}
+		fmt.Printf("🎲 使用指定基准种子: %d\n", seed)
+		return seed, nil
+	}
+
+	seed := time.Now().UnixNano()
+	fmt.Printf("🎲 本次运行基准种子: %d (可通过 --seed %d 复现)\n", seed, seed)
+	return seed, nil
+}
+
+// taskSeed 由基准种子和任务坐标（游戏、档位、第几次）派生单个任务的种子
+// 派生结果会写入输出文件，regenerate 直接使用文件中的任务种子
+func taskSeed(baseSeed int64, gameID int, rtpLevel float64, testNumber int) int64 {
+	return baseSeed ^ int64(gameID)*1_000_003 ^ int64(testNumber)*1_000_033 ^ int64(rtpLevel)*1_000_037
+}
+
+// gameOutputDir 游戏输出目录：普通模式 output/<gameId>，购买夺宝模式 output/<gameId>_fb
+func gameOutputDir(gameID int, isFb bool) string {
+	if isFb {
+		return filepath.Join(outputRoot, fmt.Sprintf("%d_fb", gameID))
+	}
+	return filepath.Join(outputRoot, fmt.Sprintf("%d", gameID))
+}
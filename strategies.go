@@ -0,0 +1,1363 @@
+package main
+
+import (
+	"fmt"
+	"math"
+	"sort"
+)
+
+// generateStrategy 标准策略：随机抽取中奖数据逼近目标金额，不足时在内存中精确补齐，再用不中奖数据补满
+type generateStrategy struct{}
+
+func (generateStrategy) Name() string { return "generate" }
+
+func (generateStrategy) Description() string {
+	return "标准策略：随机中奖数据逼近目标金额 + 精确补齐 + 不中奖数据补满"
+}
+
+func (generateStrategy) Levels(config *Config) []RtpLevel { return config.NormalLevels() }
+
+// RtpWindow 默认目标 [rtp, rtp*1.005]
+func (generateStrategy) RtpWindow(config *Config, level RtpLevel) (float64, float64) {
+	return level.Rtp, level.Rtp * 1.005
+}
+
+func (generateStrategy) Shape(config *Config) StrategyShape {
+	return StrategyShape{
+		DataNum:    config.Tables.DataNum,
+		TestNum:    config.Tables.DataTableNum,
+		PerSpinBet: MoneyFromFloat(config.Bet.CS * config.Bet.ML * config.Bet.BL),
+		Quotas:     defaultPrizeQuotas(config, config.Tables.DataNum),
+	}
+}
+
+func (generateStrategy) LoadPools(db *Database) (*CandidatePools, error) {
+	winDataAll, err := db.GetWinData()
+	if err != nil {
+		return nil, fmt.Errorf("获取中奖数据失败: %v", err)
+	}
+	noWinDataAll, err := db.GetNoWinData()
+	if err != nil {
+		return nil, fmt.Errorf("获取不中奖数据失败: %v", err)
+	}
+	return &CandidatePools{Win: winDataAll, NoWin: noWinDataAll}, nil
+}
+
+// Select 执行单次RTP测试
+func (generateStrategy) Select(in *SelectionInput) (*SelectionResult, error) {
+	printf := in.Logf
+	rtpLevel, rtp, totalBet := in.RtpLevel, in.Rtp, in.TotalBet
+	winDataAll, noWinDataAll := in.Pools.Win, in.Pools.NoWin
+
+	//计算允许中的金额以及验收区间对应的金额上下限
+	allowWin := totalBet.MulRatio(rtp)
+	minWin := totalBet.MulRatio(in.MinRtp)
+	maxWin := totalBet.MulRatio(in.MaxRtp)
+
+	//从所有中奖数据, 中随机获取, 但是大奖, 巨奖, 超级巨奖不能大于配置的值
+	bigNum := in.Quotas.Big
+	megaNum := in.Quotas.Mega
+	superMegaNum := in.Quotas.SuperMega
+
+	// 使用共享只读中奖数据
+	printf("\n获取到中奖数据: %d条\n", len(winDataAll))
+	printf("档位: %.0f, 目标RTP: %.4f, 允许中奖金额: %.2f\n", rtpLevel, rtp, allowWin)
+	printf("🎯 RTP验收区间 [%.4f, %.4f], 中奖金额范围 [%.2f, %.2f]\n", in.MinRtp, in.MaxRtp, minWin, maxWin)
+
+	// 第一步：从中奖数据中填充, 直到达到目标金额或数量限制
+	var data []GameResultData
+	var totalWin Money
+	bigCount := 0
+	megaCount := 0
+	superMegaCount := 0
+
+	// 任务内乱序索引（避免共享切片原地打乱）
+	rng := in.Rng
+	permWin := rng.Perm(len(winDataAll))
+
+	for _, idx := range permWin {
+		item := winDataAll[idx]
+		// 检查是否已经达到数量限制（RTP 2.0特殊处理）
+		if rtp >= 2.0 && len(data) >= in.Count {
+			printf("⚠️ RTP %.0f档位：已达到数量限制 %d 条, 停止添加中奖数据\n", rtpLevel, in.Count)
+			break
+		}
+
+		// 判断本条是什么奖励配额（仅检查, 不计数, 计数在成功加入后再做）
+		gwt := item.GWT
+		switch gwt {
+		case 2: // 大奖
+			if bigCount >= bigNum {
+				continue
+			}
+		case 3: // 巨奖
+			if megaCount >= megaNum {
+				continue
+			}
+		case 4: // 超级巨奖
+			if superMegaCount >= superMegaNum {
+				continue
+			}
+		}
+
+		// 计算加入这条数据后的总中奖金额（先计算, 再决定是否加入）
+		newTotalWin := totalWin + item.AW
+		if newTotalWin > maxWin {
+			continue
+		}
+		totalWin += item.AW
+		// 添加数据并更新累计
+		data = append(data, item)
+		// 成功加入后再更新对应奖励计数
+		switch gwt {
+		case 2:
+			bigCount++
+		case 3:
+			megaCount++
+		case 4:
+			superMegaCount++
+		}
+		//这里应该是计算偏差
+		if totalWin >= allowWin && totalWin <= maxWin {
+			printf("达到目标范围中奖金额, 当前中奖总额: %.2f, 目标中奖金额: %.2f\n", totalWin, allowWin)
+			break
+		}
+
+		// 目标低于区间上限时（如区间 [1.9, 2.0]）：达到下限后继续逼近目标，直到达到数量限制
+		if totalWin >= minWin && len(data) >= in.Count {
+			printf("🎯 档位%.0f已达到数量限制 %d 条, 当前RTP: %.4f, 目标RTP: %.4f\n", rtpLevel, in.Count, totalWin.Ratio(totalBet), rtp)
+			break
+		}
+	}
+	printf("⚠️ !!!当前中奖总额 %.2f 目标 %.2f,据...\n", totalWin, allowWin)
+	// 检查是否达到验收区间下限, 如果没有达到则精确补齐
+	if totalWin < minWin {
+		printf("⚠️ 当前中奖总额 %.2f 未达到下限 %.2f, 开始精确补齐...\n", totalWin, minWin)
+
+		filled, err := topUpWinExact(data, winDataAll, minWin, maxWin, in.Count, in.Quotas, rng, printf)
+		if _, inconclusive := err.(*FillSearchLimitError); inconclusive {
+			return nil, fmt.Errorf("❌ 档位 %.0f 精确补齐未完成（搜索超出上限，未判定为无解）: %v", rtpLevel, err)
+		}
+		if err != nil {
+			return nil, fmt.Errorf("❌ 档位 %.0f 精确补齐失败: %v", rtpLevel, err)
+		}
+		data = filled
+		stats := newSelectionResult(data, totalBet)
+		totalWin = stats.TotalWin
+
+		printf("选取中奖数据: %d条, 中奖总额: %.2f\n", len(data), totalWin)
+		printf("大奖: %d/%d, 巨奖: %d/%d, 超级巨奖: %d/%d\n",
+			stats.BigCount, bigNum, stats.MegaCount, megaNum, stats.SuperMegaCount, superMegaNum)
+		printf("✅ 补充后达到目标, 当前: %.2f, 下限: %.2f\n", totalWin, minWin)
+		printf("✅ RTP偏差: %.6f (当前: %.6f, 目标: %.6f)\n",
+			math.Abs(totalWin.Ratio(totalBet)-rtp), totalWin.Ratio(totalBet), rtp)
+	}
+
+	// 第二步：用不中奖数据补全到1万条
+	needNum := in.Count - len(data)
+	printf("📊 数据量统计: 目标 %d 条, 已有中奖数据 %d 条, 需要补全 %d 条\n",
+		in.Count, len(data), needNum)
+
+	if needNum > 0 {
+		// 使用共享只读的不中奖数据, 任务内自建乱序索引
+		printf("获取到不中奖数据: %d条, 需要补全: %d条\n", len(noWinDataAll), needNum)
+
+		if len(noWinDataAll) > 0 {
+			// 使用与本任务相同的 rng 生成不中奖数据的乱序索引
+			permNo := rng.Perm(len(noWinDataAll))
+			// 补全数据, 如果不中奖数据不够则重复使用
+			for i := 0; i < needNum; i++ {
+				idx := permNo[i%len(permNo)]
+				data = append(data, noWinDataAll[idx])
+			}
+		} else {
+			// 如果没有不中奖数据, 用中奖数据重复填充（这种情况很少见）
+			printf("⚠️ 没有不中奖数据, 使用中奖数据重复填充\n")
+			for i := 0; i < needNum; i++ {
+				idx := permWin[i%len(permWin)]
+				data = append(data, winDataAll[idx])
+			}
+		}
+	}
+
+	// 重新计算最终RTP（包含所有数据）
+	var finalTotalWin Money
+	for _, item := range data {
+		finalTotalWin += item.AW
+	}
+	finalRTP := finalTotalWin.Ratio(totalBet)
+
+	// 计算RTP偏差
+	rtpDeviation := math.Abs(finalRTP - rtp)
+	printf("✅ 档位: %.0f,📊 最终统计: 总投注 %.2f, 总中奖 %.2f, 实际RTP %.6f, 目标: %0.6f,实际金额: %.2f,预期金额下限: %.2f,预期金额上限: %.2f, RTP偏差: %.6f \n", rtpLevel, totalBet, finalTotalWin, finalRTP, rtp, finalTotalWin, minWin, maxWin, rtpDeviation)
+
+	// 最终验证数据量
+	printf("🔍 最终验证: 期望 %d 条, 实际 %d 条\n", in.Count, len(data))
+	if len(data) != in.Count {
+		return nil, fmt.Errorf("❌ 数据量不匹配：期望 %d 条, 实际 %d 条", in.Count, len(data))
+	}
+	if err := checkRtpWindow(rtpLevel, finalTotalWin, minWin, maxWin, in, printf); err != nil {
+		return nil, err
+	}
+
+	return newSelectionResult(data, totalBet), nil
+}
+
+// generate2Strategy 四阶段策略：随机中奖 + 动态占比(盈利/不盈利) + 大额补齐 + 不中奖兜底
+type generate2Strategy struct{}
+
+func (generate2Strategy) Name() string { return "generate2" }
+
+func (generate2Strategy) Description() string {
+	return "四阶段策略：随机中奖 + 动态占比(盈利/不盈利) + 大额补齐 + 不中奖兜底"
+}
+
+func (generate2Strategy) Levels(config *Config) []RtpLevel { return config.NormalLevels() }
+
+// RtpWindow 默认目标 [rtp, rtp*(1+upper_deviation)]
+func (generate2Strategy) RtpWindow(config *Config, level RtpLevel) (float64, float64) {
+	return level.Rtp, level.Rtp * (1 + config.StageRatios.UpperDeviation)
+}
+
+func (generate2Strategy) Shape(config *Config) StrategyShape {
+	return StrategyShape{
+		DataNum:    config.Tables.DataNum,
+		TestNum:    config.Tables.DataTableNum,
+		PerSpinBet: MoneyFromFloat(config.Bet.CS * config.Bet.ML * config.Bet.BL),
+		Quotas:     defaultPrizeQuotas(config, config.Tables.DataNum),
+	}
+}
+
+func (generate2Strategy) LoadPools(db *Database) (*CandidatePools, error) {
+	// 使用三种数据源
+	fmt.Println("🔄 正在获取中奖但不盈利数据...")
+	winDataAll, err := db.GetWinData()
+	if err != nil {
+		return nil, fmt.Errorf("获取中奖但不盈利数据失败: %v", err)
+	}
+	fmt.Printf("✅ 中奖但不盈利数据条数: %d\n", len(winDataAll))
+
+	fmt.Println("🔄 正在获取中奖且盈利数据...")
+	profitDataAll, err := db.GetProfitData()
+	if err != nil {
+		return nil, fmt.Errorf("获取中奖且盈利数据失败: %v", err)
+	}
+	fmt.Printf("✅ 中奖且盈利数据条数: %d\n", len(profitDataAll))
+
+	fmt.Println("🔄 正在获取不中奖数据...")
+	noWinDataAll, err := db.GetNoWinData()
+	if err != nil {
+		return nil, fmt.Errorf("获取不中奖数据失败: %v", err)
+	}
+	fmt.Printf("✅ 不中奖数据条数: %d\n", len(noWinDataAll))
+
+	if len(winDataAll) == 0 {
+		return nil, fmt.Errorf("未获取到中奖但不盈利数据，无法继续")
+	}
+	if len(noWinDataAll) == 0 {
+		fmt.Println("⚠️ 未获取到不中奖数据，后续将无法补全至目标条数。")
+	}
+	return &CandidatePools{Win: winDataAll, Profit: profitDataAll, NoWin: noWinDataAll}, nil
+}
+
+// Select 执行单次RTP测试 - 四阶段策略版本
+func (generate2Strategy) Select(in *SelectionInput) (*SelectionResult, error) {
+	config, printf := in.Config, in.Logf
+	rtpLevel, rtp, totalBet := in.RtpLevel, in.Rtp, in.TotalBet
+	winDataAll, noWinDataAll, profitDataAll := in.Pools.Win, in.Pools.NoWin, in.Pools.Profit
+
+	// 计算允许中奖金额和配置参数
+	allowWin := totalBet.MulRatio(rtp)
+	lowerBound := totalBet.MulRatio(in.MinRtp)
+	upperBound := totalBet.MulRatio(in.MaxRtp)
+	perSpinBet := in.PerSpinBet
+
+	// 奖项数量限制
+	bigNum := in.Quotas.Big
+	megaNum := in.Quotas.Mega
+	superMegaNum := in.Quotas.SuperMega
+
+	printf("档位: %.0f, 目标RTP: %.4f, 允许中奖金额: %.2f, 上限: %.2f\n", rtpLevel, rtp, allowWin, upperBound)
+	printf("🎯 RTP验收区间 [%.4f, %.4f]\n", in.MinRtp, in.MaxRtp)
+	printf("候选数据: win(not-profit)=%d, profit=%d, nowin=%d\n", len(winDataAll), len(profitDataAll), len(noWinDataAll))
+	printf("奖项限制: 大奖=%d, 巨奖=%d, 超级巨奖=%d\n", bigNum, megaNum, superMegaNum)
+
+	// 随机源
+	rng := in.Rng
+
+	// 结果容器和计数器
+	var data []GameResultData
+	var totalWin Money
+	targetCount := in.Count
+	bigCount := 0
+	megaCount := 0
+	superMegaCount := 0
+
+	// 已使用ID，避免重复
+	used := make(map[int]struct{}, targetCount)
+
+	// 辅助函数：尝试加入一条记录（检查奖项限制、去重、上限）
+	tryAppend := func(item GameResultData) bool {
+		if _, ok := used[item.ID]; ok {
+			return false
+		}
+
+		// 检查奖项数量限制
+		switch item.GWT {
+		case 2: // 大奖
+			if bigCount >= bigNum {
+				return false
+			}
+		case 3: // 巨奖
+			if megaCount >= megaNum {
+				return false
+			}
+		case 4: // 超级巨奖
+			if superMegaCount >= superMegaNum {
+				return false
+			}
+		}
+
+		if item.AW <= 0 {
+			return false
+		}
+
+		// 检查是否超过上限
+		if totalWin+item.AW > upperBound {
+			return false
+		}
+
+		// 添加数据并更新计数
+		data = append(data, item)
+		totalWin += item.AW
+		used[item.ID] = struct{}{}
+
+		// 更新奖项计数
+		switch item.GWT {
+		case 2:
+			bigCount++
+		case 3:
+			megaCount++
+		case 4:
+			superMegaCount++
+		}
+		return true
+	}
+
+	// 随机化阶段1比例
+	stage1Ratio := config.StageRatios.Stage1MinRatio + rng.Float64()*(config.StageRatios.Stage1MaxRatio-config.StageRatios.Stage1MinRatio)
+	stage1Count := int(math.Round(float64(targetCount) * stage1Ratio))
+
+	// 阶段1：打乱 winDataAll，单轮无放回采样
+	if len(winDataAll) > 0 && stage1Count > 0 {
+		perm := rng.Perm(len(winDataAll))
+		for _, idx := range perm {
+			if len(data) >= stage1Count {
+				break
+			}
+			_ = tryAppend(winDataAll[idx])
+		}
+		printf("阶段1：已加入 %d 条（目标 %.1f%%=%d），累计中奖=%.2f\n", len(data), stage1Ratio*100, stage1Count, totalWin)
+	}
+
+	// 阶段2：动态占比（profit vs win），根据缺口/剩余名额决定倾向
+	if totalWin < allowWin && len(data) < targetCount && (len(profitDataAll) > 0 || len(winDataAll) > 0) {
+		permProfit := rng.Perm(len(profitDataAll))
+		permWin2 := rng.Perm(len(winDataAll))
+		pi, wi := 0, 0
+
+		// 估算初始倾向
+		remainingSlots := targetCount - len(data)
+		remainingWin := allowWin - totalWin
+		needFactor := 0.0
+		if remainingSlots > 0 {
+			needFactor = remainingWin.Ratio(perSpinBet * Money(remainingSlots))
+		}
+		basePProfit := needFactor
+		if basePProfit < 0.2 {
+			basePProfit = 0.2
+		}
+		if basePProfit > 0.8 {
+			basePProfit = 0.8
+		}
+		printf("阶段2：动态占比起始 pProfit=%.3f (needFactor=%.3f)\n", basePProfit, needFactor)
+
+		maxOuter := len(profitDataAll) + len(winDataAll) + 1024
+		for outer := 0; outer < maxOuter; outer++ {
+			if totalWin >= allowWin || len(data) >= targetCount {
+				break
+			}
+
+			// 实时更新占比
+			remainingSlots = targetCount - len(data)
+			remainingWin = allowWin - totalWin
+			if remainingSlots <= 0 || remainingWin <= 0 {
+				break
+			}
+			needFactor = remainingWin.Ratio(perSpinBet * Money(remainingSlots))
+			pProfit := needFactor
+			if pProfit < 0.2 {
+				pProfit = 0.2
+			}
+			if pProfit > 0.8 {
+				pProfit = 0.8
+			}
+
+			chooseProfit := rng.Float64() < pProfit
+			appended := false
+
+			if chooseProfit && pi < len(permProfit) {
+				for pi < len(permProfit) {
+					cand := profitDataAll[permProfit[pi]]
+					pi++
+					if tryAppend(cand) {
+						appended = true
+						break
+					}
+				}
+			}
+
+			// 若未能加入或无可用 profit，则尝试 win
+			if !appended && wi < len(permWin2) {
+				for wi < len(permWin2) {
+					cand := winDataAll[permWin2[wi]]
+					wi++
+					if tryAppend(cand) {
+						appended = true
+						break
+					}
+				}
+			}
+
+			// 若先选 win 失败，再尝试 profit 兜底
+			if !appended && !chooseProfit && pi < len(permProfit) {
+				for pi < len(permProfit) {
+					cand := profitDataAll[permProfit[pi]]
+					pi++
+					if tryAppend(cand) {
+						appended = true
+						break
+					}
+				}
+			}
+
+			// 两边都无法加入，提前退出
+			if !appended {
+				break
+			}
+		}
+		printf("阶段2完成：累计中奖=%.2f, 目标=%.2f, 数量=%d/%d\n", totalWin, allowWin, len(data), targetCount)
+	}
+
+	// 阶段3：若还需要补充（数量未达标），先用 winDataAll 的大额补充
+	if len(data) < targetCount {
+		remainingSlots := targetCount - len(data)
+		stage3aSlots := int(math.Ceil(float64(remainingSlots) * config.StageRatios.Stage3WinTopRatio))
+
+		if stage3aSlots > 0 && len(winDataAll) > 0 {
+			// winDataAll 按 aw DESC
+			winDesc := make([]GameResultData, len(winDataAll))
+			copy(winDesc, winDataAll)
+			sort.SliceStable(winDesc, func(i, j int) bool { return winDesc[i].AW > winDesc[j].AW })
+			for _, it := range winDesc {
+				if stage3aSlots == 0 || len(data) >= targetCount {
+					break
+				}
+				if tryAppend(it) {
+					stage3aSlots--
+				}
+			}
+		}
+
+		// 阶段3b：剩余名额根据缺口大小，用 profitDataAll 小额或大额补齐
+		if len(data) < targetCount {
+			remainingSlots = targetCount - len(data)
+			remainingWin := allowWin - totalWin
+			gapSmallThreshold := allowWin.MulRatio(0.02) // 小缺口阈值
+			if perSpinBet > gapSmallThreshold {
+				gapSmallThreshold = perSpinBet
+			}
+
+			// 若金额已足或接近上限，则直接跳过到数量兜底
+			if remainingWin > 0 && len(profitDataAll) > 0 {
+				// 按需选择排序方向
+				profit := make([]GameResultData, len(profitDataAll))
+				copy(profit, profitDataAll)
+				if remainingWin <= gapSmallThreshold {
+					sort.SliceStable(profit, func(i, j int) bool { return profit[i].AW < profit[j].AW }) // 小额优先
+				} else {
+					sort.SliceStable(profit, func(i, j int) bool { return profit[i].AW > profit[j].AW }) // 大额优先
+				}
+
+				for _, it := range profit {
+					if remainingSlots == 0 || len(data) >= targetCount {
+						break
+					}
+					// 若已经达到目标金额，仅在不超过上限时允许继续；核心由上限约束
+					if tryAppend(it) {
+						remainingSlots--
+						remainingWin = allowWin - totalWin
+						if remainingWin <= 0 {
+							// 金额已达标，后续数量不足交由阶段4处理
+							break
+						}
+					}
+				}
+			}
+		}
+		printf("阶段3完成：累计中奖=%.2f, 数量=%d/%d\n", totalWin, len(data), targetCount)
+	}
+
+	// 阶段4：数量兜底，优先无放回补不中奖；若仍不足，再允许重复不中奖补满
+	if len(data) < targetCount && len(noWinDataAll) > 0 {
+		need := targetCount - len(data)
+		// 先无放回
+		perm := rng.Perm(len(noWinDataAll))
+		for _, idx := range perm {
+			if need == 0 {
+				break
+			}
+			item := noWinDataAll[idx]
+			if _, ok := used[item.ID]; ok {
+				continue
+			}
+			data = append(data, item)
+			used[item.ID] = struct{}{}
+			need--
+		}
+		// 再重复补齐（仅对不中奖允许重复，以保证条数）
+		if need > 0 {
+			for i := 0; i < need; i++ {
+				data = append(data, noWinDataAll[i%len(noWinDataAll)])
+			}
+		}
+		printf("阶段4完成：补充不中奖数据，最终数量=%d/%d\n", len(data), targetCount)
+	}
+
+	// 重新计算最终RTP（包含所有数据）
+	var finalTotalWin Money
+	for _, item := range data {
+		finalTotalWin += item.AW
+	}
+	finalRTP := finalTotalWin.Ratio(totalBet)
+
+	// 计算RTP偏差
+	rtpDeviation := math.Abs(finalRTP - rtp)
+	printf("✅ 档位: %.0f,📊 最终统计: 总投注 %.2f, 总中奖 %.2f, 实际RTP %.6f, 目标: %0.6f, RTP偏差: %.6f\n", rtpLevel, totalBet, finalTotalWin, finalRTP, rtp, rtpDeviation)
+	printf("🔍 奖项统计: 大奖: %d/%d, 巨奖: %d/%d, 超级巨奖: %d/%d\n", bigCount, bigNum, megaCount, megaNum, superMegaCount, superMegaNum)
+
+	// 最终验证数据量
+	printf("🔍 最终验证: 期望 %d 条, 实际 %d 条\n", targetCount, len(data))
+	if len(data) != targetCount {
+		return nil, fmt.Errorf("❌ 数据量不匹配：期望 %d 条, 实际 %d 条", targetCount, len(data))
+	}
+	if err := checkRtpWindow(rtpLevel, finalTotalWin, lowerBound, upperBound, in, printf); err != nil {
+		return nil, err
+	}
+
+	// 重复率统计（按 id 去重）
+	uniq := make(map[int]int, len(data))
+	for _, it := range data {
+		uniq[it.ID]++
+	}
+	dupCount := 0
+	for _, c := range uniq {
+		if c > 1 {
+			dupCount += c - 1
+		}
+	}
+	dupRate := 0.0
+	if n := len(data); n > 0 {
+		dupRate = float64(dupCount) / float64(n)
+	}
+	printf("🔎 去重统计: 总数=%d, 唯一=%d, 重复=%d, 重复率=%.4f\n", len(data), len(uniq), dupCount, dupRate)
+
+	return newSelectionResult(data, totalBet), nil
+}
+
+// generate3Strategy V3策略：按RTP动态分配不中奖/不盈利/盈利比例，保证RTP下限并精确控制数量
+type generate3Strategy struct{}
+
+func (generate3Strategy) Name() string { return "generate3" }
+
+func (generate3Strategy) Description() string {
+	return "V3策略：10%不中奖 + 40%不盈利 + 30%盈利数据（按RTP动态调整比例）"
+}
+
+func (generate3Strategy) Levels(config *Config) []RtpLevel { return config.TestLevels() }
+
+// RtpWindow 默认目标 [rtp-0.1, rtp+0.5]
+func (generate3Strategy) RtpWindow(config *Config, level RtpLevel) (float64, float64) {
+	return level.Rtp - 0.1, level.Rtp + 0.5
+}
+
+func (generate3Strategy) Shape(config *Config) StrategyShape {
+	return StrategyShape{
+		DataNum:         config.Tables.DataNumV3,
+		TestNum:         config.Tables.DataTableNum3,
+		PerSpinBet:      MoneyFromFloat(config.Bet.CS * config.Bet.ML * config.Bet.BL),
+		Quotas:          defaultPrizeQuotas(config, config.Tables.DataNumV3),
+		UnlimitedPrizes: true,
+	}
+}
+
+func (generate3Strategy) LoadPools(db *Database) (*CandidatePools, error) {
+	winDataAll, err := db.GetWinData()
+	if err != nil {
+		return nil, fmt.Errorf("获取中奖数据失败: %v", err)
+	}
+	noWinDataAll, err := db.GetNoWinData()
+	if err != nil {
+		return nil, fmt.Errorf("获取不中奖数据失败: %v", err)
+	}
+	return &CandidatePools{Win: winDataAll, NoWin: noWinDataAll}, nil
+}
+
+// Select 执行单次RTP测试V3 - 优化版本：动态比例调整+RTP下限保证+数量精确控制
+// V3 策略不限制大奖/巨奖/超级巨奖数量
+func (generate3Strategy) Select(in *SelectionInput) (*SelectionResult, error) {
+	printf := in.Logf
+	rtpLevel, rtp, totalBet := in.RtpLevel, in.Rtp, in.TotalBet
+	winDataAll, noWinDataAll := in.Pools.Win, in.Pools.NoWin
+
+	// 计算允许中的金额
+	allowWin := totalBet.MulRatio(rtp)
+	rtpUpperLimit := in.MaxRtp
+	maxAllowWin := totalBet.MulRatio(rtpUpperLimit)
+	rtpLowerLimit := in.MinRtp // RTP下限：默认为目标值-0.1
+	minAllowWin := totalBet.MulRatio(rtpLowerLimit)
+
+	// 数据统计
+	printf("\n数据源统计:\n")
+	printf("  - 中奖数据(包含不盈利): %d条\n", len(winDataAll))
+	printf("  - 不中奖数据(aw=0): %d条\n", len(noWinDataAll))
+	printf("档位: %.0f, 目标RTP: %.4f, 允许中奖金额: %.2f\n", rtpLevel, rtp, allowWin)
+	printf("🔧 V3优化策略：动态比例调整 + RTP下限保证 + 数量精确控制\n")
+	printf("RTP控制范围: [%.2f, %.2f]，中奖金额范围: [%.2f, %.2f]\n", rtpLowerLimit, rtpUpperLimit, minAllowWin, maxAllowWin)
+
+	// 任务随机源
+	rng := in.Rng
+
+	// 动态计算各阶段的数量目标（根据RTP目标调整）
+	totalCount := in.Count
+	var noWinCount, notProfitCount, profitCount, remainingCount int
+
+	// 根据RTP目标动态调整比例
+	if rtp >= 3.0 {
+		// 超高RTP：需要更多盈利数据
+		noWinCount = int(float64(totalCount) * 0.05)     // 5%不中奖
+		notProfitCount = int(float64(totalCount) * 0.25) // 25%不盈利
+		profitCount = int(float64(totalCount) * 0.50)    // 50%盈利
+		remainingCount = int(float64(totalCount) * 0.20) // 20%调整
+	} else if rtp >= 2.0 {
+		// 高RTP：增加盈利数据比例
+		noWinCount = int(float64(totalCount) * 0.08)     // 8%不中奖
+		notProfitCount = int(float64(totalCount) * 0.32) // 32%不盈利
+		profitCount = int(float64(totalCount) * 0.40)    // 40%盈利
+		remainingCount = int(float64(totalCount) * 0.20) // 20%调整
+	} else {
+		// 中低RTP：保持原比例
+		noWinCount = int(float64(totalCount) * 0.10)     // 10%不中奖
+		notProfitCount = int(float64(totalCount) * 0.40) // 40%不盈利
+		profitCount = int(float64(totalCount) * 0.30)    // 30%盈利
+		remainingCount = int(float64(totalCount) * 0.20) // 20%调整
+	}
+
+	printf("🎯 动态数据分配计划 (RTP=%.2f):\n", rtp)
+	printf("  - 不中奖数据: %d 条 (%.1f%%)\n", noWinCount, float64(noWinCount)/float64(totalCount)*100)
+	printf("  - 不盈利数据: %d 条 (%.1f%%)\n", notProfitCount, float64(notProfitCount)/float64(totalCount)*100)
+	printf("  - 盈利数据: %d 条 (%.1f%%)\n", profitCount, float64(profitCount)/float64(totalCount)*100)
+	printf("  - 剩余调整: %d 条 (%.1f%%)\n", remainingCount, float64(remainingCount)/float64(totalCount)*100)
+
+	var data []GameResultData
+	var totalWin Money
+	perSpinBet := in.PerSpinBet
+
+	// 第一步：添加不中奖数据 (10%)
+	printf("\n📊 第一步：添加不中奖数据\n")
+	if len(noWinDataAll) > 0 {
+		permNo := rng.Perm(len(noWinDataAll))
+		for i := 0; i < noWinCount && i < len(permNo); i++ {
+			idx := permNo[i]
+			data = append(data, noWinDataAll[idx])
+		}
+		printf("✅ 添加不中奖数据: %d 条\n", noWinCount)
+	}
+
+	// 第二步：添加不盈利数据 (40%)
+	printf("\n📊 第二步：添加不盈利数据\n")
+	// 从winDataAll中筛选出不盈利数据 (aw > 0 且 aw <= tb)
+	var notProfitData []GameResultData
+	for _, item := range winDataAll {
+		if item.AW > 0 && item.AW <= item.TB {
+			notProfitData = append(notProfitData, item)
+		}
+	}
+	printf("可用不盈利数据: %d 条\n", len(notProfitData))
+
+	if len(notProfitData) > 0 {
+		permNotProfit := rng.Perm(len(notProfitData))
+		addedCount := 0
+		for i := 0; i < len(permNotProfit) && addedCount < notProfitCount; i++ {
+			idx := permNotProfit[i]
+			item := notProfitData[idx]
+			data = append(data, item)
+			totalWin += item.AW
+			addedCount++
+		}
+		printf("✅ 添加不盈利数据: %d 条，累计中奖金额: %.2f\n", addedCount, totalWin)
+	}
+
+	// 第三步：添加盈利数据，动态调整筛选条件
+	printf("\n📊 第三步：添加盈利数据\n")
+
+	// 根据RTP目标动态调整盈利数据筛选条件
+	var profitMinRatio, profitMaxMultiplier float64
+	if rtp >= 3.0 {
+		profitMinRatio = 1.2     // 超高RTP：放宽下限
+		profitMaxMultiplier = 15 // 提高上限
+	} else if rtp >= 2.0 {
+		profitMinRatio = 1.3     // 高RTP：适度放宽
+		profitMaxMultiplier = 10 // 适度提高上限
+	} else {
+		profitMinRatio = 1.5    // 中低RTP：保持原条件
+		profitMaxMultiplier = 6 // 保持原上限
+	}
+
+	profitUpperLimit := perSpinBet.MulRatio(rtp * profitMaxMultiplier)
+	printf("盈利数据筛选条件: aw > %.1f*tb 且 aw <= %.1f*tb (上限: %.2f)\n",
+		profitMinRatio, rtp*profitMaxMultiplier, profitUpperLimit)
+
+	// 筛选盈利数据：动态条件
+	var suitableProfitData []GameResultData
+	for _, item := range winDataAll {
+		if item.AW > item.TB.MulRatio(profitMinRatio) && item.AW <= profitUpperLimit {
+			suitableProfitData = append(suitableProfitData, item)
+		}
+	}
+	printf("可用盈利数据: %d 条\n", len(suitableProfitData))
+
+	// 按AW降序排序，优先选择大额盈利数据
+	sort.SliceStable(suitableProfitData, func(i, j int) bool {
+		return suitableProfitData[i].AW > suitableProfitData[j].AW
+	})
+
+	addedProfitCount := 0
+	var currentProfitWin Money
+	for _, item := range suitableProfitData {
+		if addedProfitCount >= profitCount {
+			break
+		}
+
+		// 检查加入这条数据后是否超过RTP上限
+		newTotalWin := totalWin + item.AW
+		if newTotalWin > maxAllowWin {
+			continue
+		}
+
+		data = append(data, item)
+		totalWin += item.AW
+		currentProfitWin += item.AW
+		addedProfitCount++
+	}
+	printf("✅ 添加盈利数据: %d 条，盈利金额: %.2f，累计中奖金额: %.2f\n", addedProfitCount, currentProfitWin, totalWin)
+
+	// 第四步：智能调整剩余数据 - 优化版本
+	printf("\n📊 第四步：智能调整剩余数据\n")
+	currentCount := len(data)
+	needMore := totalCount - currentCount
+	printf("当前数据量: %d，目标: %d，还需要: %d\n", currentCount, totalCount, needMore)
+
+	// 计算当前RTP与目标的差距
+	currentRTP := totalWin.Ratio(totalBet)
+	rtpGap := currentRTP - rtp
+	printf("当前RTP: %.6f，目标RTP: %.6f，差距: %.6f\n", currentRTP, rtp, rtpGap)
+
+	// 确保数量达标
+	if needMore > 0 {
+		printf("🎯 需要补充 %d 条数据以达到目标数量\n", needMore)
+
+		// 计算还需要多少中奖金额才能达到RTP下限
+		remainingWinNeeded := minAllowWin - totalWin
+		printf("还需要中奖金额: %.2f 才能达到RTP下限 (%.2f)\n", remainingWinNeeded, rtpLowerLimit)
+
+		// 收集所有可用数据（去重）
+		usedIds := make(map[int]bool)
+		for _, item := range data {
+			usedIds[item.ID] = true
+		}
+
+		var allAvailableData []GameResultData
+		// 优先使用盈利数据
+		for _, item := range suitableProfitData {
+			if !usedIds[item.ID] {
+				allAvailableData = append(allAvailableData, item)
+			}
+		}
+		// 其次使用不盈利数据
+		for _, item := range notProfitData {
+			if !usedIds[item.ID] {
+				allAvailableData = append(allAvailableData, item)
+			}
+		}
+		// 最后使用不中奖数据
+		for _, item := range noWinDataAll {
+			if !usedIds[item.ID] {
+				allAvailableData = append(allAvailableData, item)
+			}
+		}
+
+		printf("可用补充数据: %d 条\n", len(allAvailableData))
+
+		if remainingWinNeeded > 0 {
+			// RTP不足，优先选择大金额数据
+			printf("🎯 RTP不足，优先选择大金额数据提升RTP\n")
+			sort.SliceStable(allAvailableData, func(i, j int) bool {
+				return allAvailableData[i].AW > allAvailableData[j].AW
+			})
+		} else {
+			// RTP已达标，优先选择中小金额数据保持平衡
+			printf("🎯 RTP已达标，优先选择中小金额数据保持平衡\n")
+			sort.SliceStable(allAvailableData, func(i, j int) bool {
+				return allAvailableData[i].AW < allAvailableData[j].AW
+			})
+		}
+
+		// 添加数据直到数量达标
+		added := 0
+		for _, item := range allAvailableData {
+			if added >= needMore {
+				break
+			}
+
+			// 检查添加后是否超过RTP上限
+			newTotalWin := totalWin + item.AW
+			if newTotalWin > maxAllowWin {
+				continue
+			}
+
+			data = append(data, item)
+			totalWin += item.AW
+			added++
+		}
+		printf("✅ 补充数据: %d 条，累计中奖金额: %.2f\n", added, totalWin)
+
+		// 如果还是不够，用不中奖数据填充（确保数量达标）
+		if len(data) < totalCount {
+			remaining := totalCount - len(data)
+			printf("🎯 还需要 %d 条数据，用不中奖数据填充确保数量达标\n", remaining)
+
+			permNo := rng.Perm(len(noWinDataAll))
+			for i := 0; i < remaining && i < len(permNo); i++ {
+				idx := permNo[i]
+				data = append(data, noWinDataAll[idx])
+			}
+			printf("✅ 不中奖数据填充: %d 条\n", remaining)
+		}
+	}
+
+	// 第五步：精确RTP调整和下限保证
+	printf("\n📊 第五步：精确RTP调整和下限保证\n")
+	finalRTP := totalWin.Ratio(totalBet)
+	rtpDeviation := math.Abs(finalRTP - rtp)
+	printf("调整前RTP: %.6f，目标RTP: %.6f，偏差: %.6f\n", finalRTP, rtp, rtpDeviation)
+
+	// 检查RTP下限（按分比较）
+	if totalWin < minAllowWin {
+		printf("⚠️ RTP低于下限 (%.2f < %.2f)，尝试提升RTP\n", finalRTP, rtpLowerLimit)
+
+		// 收集所有未使用的中奖数据
+		usedIds := make(map[int]bool)
+		for _, item := range data {
+			usedIds[item.ID] = true
+		}
+
+		var allUnusedWinData []GameResultData
+		for _, item := range winDataAll {
+			if !usedIds[item.ID] && item.AW > 0 {
+				allUnusedWinData = append(allUnusedWinData, item)
+			}
+		}
+
+		if len(allUnusedWinData) > 0 {
+			// 按AW降序排序，优先选择大额数据
+			sort.SliceStable(allUnusedWinData, func(i, j int) bool {
+				return allUnusedWinData[i].AW > allUnusedWinData[j].AW
+			})
+
+			// 尝试替换不中奖数据来提升RTP
+			adjustmentCount := 0
+			maxAdjustments := 200 // 增加调整次数
+
+			for _, newItem := range allUnusedWinData {
+				if adjustmentCount >= maxAdjustments {
+					break
+				}
+
+				// 找到一条不中奖数据进行替换
+				for i, oldItem := range data {
+					if oldItem.AW == 0 { // 只替换不中奖数据
+						// 计算替换后的RTP
+						replaceTotalWin := totalWin - oldItem.AW + newItem.AW
+						replaceRTP := replaceTotalWin.Ratio(totalBet)
+
+						// 如果替换后RTP更接近目标且不超过上限
+						if replaceTotalWin >= minAllowWin && replaceTotalWin <= maxAllowWin {
+							data[i] = newItem
+							totalWin = replaceTotalWin
+							finalRTP = replaceRTP
+							adjustmentCount++
+							printf("🔄 替换不中奖数据: 旧AW=%.2f -> 新AW=%.2f, 新RTP=%.6f\n",
+								oldItem.AW, newItem.AW, replaceRTP)
+							break
+						}
+					}
+				}
+			}
+
+			printf("✅ RTP下限调整完成，调整了 %d 条数据，最终RTP: %.6f\n", adjustmentCount, finalRTP)
+		}
+	}
+
+	// 如果RTP偏差仍然较大，尝试微调
+	if math.Abs(finalRTP-rtp) > 0.05 {
+		printf("🎯 RTP偏差较大 (%.6f)，尝试微调\n", math.Abs(finalRTP-rtp))
+
+		// 收集所有未使用的数据
+		usedIds := make(map[int]bool)
+		for _, item := range data {
+			usedIds[item.ID] = true
+		}
+
+		var allUnusedData []GameResultData
+		for _, item := range winDataAll {
+			if !usedIds[item.ID] && item.AW > 0 {
+				allUnusedData = append(allUnusedData, item)
+			}
+		}
+
+		if len(allUnusedData) > 0 {
+			// 根据RTP偏差方向选择调整策略
+			if finalRTP < rtp {
+				// RTP偏低，优先选择大额数据
+				sort.SliceStable(allUnusedData, func(i, j int) bool {
+					return allUnusedData[i].AW > allUnusedData[j].AW
+				})
+				printf("🎯 RTP偏低，尝试添加大额数据提升RTP\n")
+			} else {
+				// RTP偏高，优先选择小额数据
+				sort.SliceStable(allUnusedData, func(i, j int) bool {
+					return allUnusedData[i].AW < allUnusedData[j].AW
+				})
+				printf("🎯 RTP偏高，尝试添加小额数据降低RTP\n")
+			}
+
+			// 尝试替换一些数据来调整RTP
+			adjustmentCount := 0
+			maxAdjustments := 100
+
+			for _, newItem := range allUnusedData {
+				if adjustmentCount >= maxAdjustments {
+					break
+				}
+
+				// 随机选择一条现有数据进行替换
+				if len(data) > 0 {
+					replaceIndex := rng.Intn(len(data))
+					oldItem := data[replaceIndex]
+
+					// 计算替换后的RTP
+					replaceTotalWin := totalWin - oldItem.AW + newItem.AW
+					replaceRTP := replaceTotalWin.Ratio(totalBet)
+					replaceDeviation := math.Abs(replaceRTP - rtp)
+
+					// 如果替换后RTP更接近目标且不超过上限
+					if replaceDeviation < rtpDeviation && replaceTotalWin <= maxAllowWin && replaceTotalWin >= minAllowWin {
+						data[replaceIndex] = newItem
+						totalWin = replaceTotalWin
+						rtpDeviation = replaceDeviation
+						adjustmentCount++
+					}
+				}
+			}
+
+			printf("✅ 精确调整完成，调整了 %d 条数据\n", adjustmentCount)
+		}
+	}
+
+	// 最终统计和验证
+	printf("\n📊 最终统计和验证\n")
+	finalRTP = totalWin.Ratio(totalBet)
+	rtpDeviation = math.Abs(finalRTP - rtp)
+
+	// 统计各类数据的数量和占比
+	var finalNoWinCount, finalNotProfitCount, finalProfitCount int
+	for _, item := range data {
+		if item.AW == 0 {
+			finalNoWinCount++
+		} else if item.AW <= item.TB {
+			finalNotProfitCount++
+		} else {
+			finalProfitCount++
+		}
+	}
+
+	printf("✅ V3优化策略结果:\n")
+	printf("  - 总数据量: %d 条\n", len(data))
+	printf("  - 总投注: %.2f\n", totalBet)
+	printf("  - 总中奖: %.2f\n", totalWin)
+	printf("  - 实际RTP: %.6f\n", finalRTP)
+	printf("  - 目标RTP: %.6f\n", rtp)
+	printf("  - RTP偏差: %.6f\n", rtpDeviation)
+	printf("  - RTP下限: %.6f (%.1f%%)\n", rtpLowerLimit, rtpLowerLimit*100)
+	printf("  - 不中奖数据: %d 条 (%.1f%%)\n", finalNoWinCount, float64(finalNoWinCount)/float64(len(data))*100)
+	printf("  - 不盈利数据: %d 条 (%.1f%%)\n", finalNotProfitCount, float64(finalNotProfitCount)/float64(len(data))*100)
+	printf("  - 盈利数据: %d 条 (%.1f%%)\n", finalProfitCount, float64(finalProfitCount)/float64(len(data))*100)
+
+	// 验证数据量
+	if len(data) != in.Count {
+		return nil, fmt.Errorf("❌ 数据量不匹配：期望 %d 条, 实际 %d 条", in.Count, len(data))
+	}
+
+	// 验证RTP区间：与其他策略一致按分比较
+	if err := checkRtpWindow(rtpLevel, totalWin, minAllowWin, maxAllowWin, in, printf); err != nil {
+		return nil, err
+	}
+
+	return newSelectionResult(data, totalBet), nil
+}
+
+// generateFbStrategy 购买夺宝策略：四阶段选择，不允许大奖/巨奖/超级巨奖
+type generateFbStrategy struct{}
+
+func (generateFbStrategy) Name() string { return "generateFb" }
+
+func (generateFbStrategy) Description() string {
+	return "购买夺宝策略：随机中奖 + 动态占比(盈利/不盈利) + 大额补齐 + 不中奖兜底"
+}
+
+func (generateFbStrategy) Levels(config *Config) []RtpLevel { return config.FbLevels() }
+
+// RtpWindow 默认目标 [rtp, rtp*1.005]
+func (generateFbStrategy) RtpWindow(config *Config, level RtpLevel) (float64, float64) {
+	return level.Rtp, level.Rtp * 1.005
+}
+
+func (generateFbStrategy) Shape(config *Config) StrategyShape {
+	// 计算总投注：cs * ml * bl * bet.fb * 数据条数；购买夺宝不允许大奖/巨奖/超级巨奖，配额为0
+	return StrategyShape{
+		DataNum:    config.Tables.DataNumFb,
+		TestNum:    config.Tables.DataTableNumFb,
+		PerSpinBet: MoneyFromFloat(config.Bet.CS * config.Bet.ML * config.Bet.BL * config.Bet.FB),
+		IsFb:       true,
+	}
+}
+
+func (generateFbStrategy) LoadPools(db *Database) (*CandidatePools, error) {
+	fmt.Println("🔄 [generateFb] 正在获取购买模式中奖数据...")
+	winDataAll, err := db.GetWinDataFb()
+	if err != nil {
+		return nil, fmt.Errorf("获取购买模式中奖数据失败: %v", err)
+	}
+	fmt.Printf("✅ [generateFb] 购买模式中奖但是不盈利的数据条数: %d\n", len(winDataAll))
+
+	profitDataAll, err := db.GetProfitDataFb()
+	if err != nil {
+		return nil, fmt.Errorf("获取购买模式盈利数据失败: %v", err)
+	}
+	fmt.Printf("✅ [generateFb] 购买模式中奖并且盈利的数据条数: %d\n", len(profitDataAll))
+
+	fmt.Println("🔄 [generateFb] 正在获取购买模式不中奖数据...")
+	noWinDataAll, err := db.GetNoWinDataFb()
+	if err != nil {
+		return nil, fmt.Errorf("获取购买模式不中奖数据失败: %v", err)
+	}
+	fmt.Printf("✅ [generateFb] 购买模式不中奖数据条数: %d\n", len(noWinDataAll))
+
+	if len(winDataAll) == 0 {
+		return nil, fmt.Errorf("未获取到购买模式中奖数据，无法继续。请检查数据条件 (aw>0, gwt<=1, fb=2, sp=true)")
+	}
+	if len(noWinDataAll) == 0 {
+		fmt.Println("⚠️ [generateFb] 未获取到购买模式不中奖数据，后续将无法补全至目标条数。")
+	}
+	return &CandidatePools{Win: winDataAll, Profit: profitDataAll, NoWin: noWinDataAll}, nil
+}
+
+// Select 生成购买夺宝 RTP 数据
+func (generateFbStrategy) Select(in *SelectionInput) (*SelectionResult, error) {
+	config, printf := in.Config, in.Logf
+	rtpLevel, rtp, totalBet := in.RtpLevel, in.Rtp, in.TotalBet
+	winDataAll, noWinDataAll, profitDataAll := in.Pools.Win, in.Pools.NoWin, in.Pools.Profit
+
+	//
+	const (
+		stage1MinRatio    = 0.60 // 第一阶段占比下限
+		stage1MaxRatio    = 0.80 // 第一阶段占比上限
+		stage3WinTopRatio = 0.90 // 第三阶段用 winDataAll 大额补齐比例
+	)
+
+	// 目标金额与边界
+	allowWin := totalBet.MulRatio(rtp)
+	lowerBound := totalBet.MulRatio(in.MinRtp)
+	upperBound := totalBet.MulRatio(in.MaxRtp)
+	perSpinBet := in.PerSpinBet
+
+	printf("[FB] allowWin=%.4f (cs=%.2f ml=%.2f bl=%.2f fb=%.2f rtp=%.4f)\n", allowWin, config.Bet.CS, config.Bet.ML, config.Bet.BL, config.Bet.FB, rtp)
+	printf("候选: win(not-profit)=%d, profit=%d, nowin=%d\n", len(winDataAll), len(profitDataAll), len(noWinDataAll))
+
+	// 随机源
+	rng := in.Rng
+
+	// 结果容器
+	var data []GameResultData
+	var totalWin Money
+	targetCount := in.Count
+	// 随机化阶段1比例 [60%, 80%]
+	stage1Ratio := stage1MinRatio + rng.Float64()*(stage1MaxRatio-stage1MinRatio)
+	stage1Count := int(math.Round(float64(targetCount) * stage1Ratio))
+
+	// 已使用ID，避免单文件内重复
+	used := make(map[int]struct{}, targetCount)
+
+	// 辅助函数：尝试加入一条记录（不超过上限，过滤大奖/巨奖/超巨奖，去重）
+	tryAppend := func(item GameResultData) bool {
+		if _, ok := used[item.ID]; ok {
+			return false
+		}
+		switch item.GWT {
+		case 2, 3, 4:
+			return false
+		}
+		if item.AW <= 0 {
+			return false
+		}
+		if totalWin+item.AW > upperBound {
+			return false
+		}
+		data = append(data, item)
+		totalWin += item.AW
+		used[item.ID] = struct{}{}
+		return true
+	}
+
+	// 阶段1：打乱 winDataAll，单轮无放回采样至 80%
+	if len(winDataAll) > 0 && stage1Count > 0 {
+		perm := rng.Perm(len(winDataAll))
+		for _, idx := range perm {
+			if len(data) >= stage1Count {
+				break
+			}
+			_ = tryAppend(winDataAll[idx])
+		}
+		printf("[FB] 阶段1：已加入 %d 条（目标 %.0f%%=%d），累计中奖=%.2f\n", len(data), stage1Ratio*100, stage1Count, totalWin)
+	}
+
+	// 阶段2：动态占比（profit vs win），根据缺口/剩余名额决定倾向，直到达到 allowWin 或数量上限
+	if totalWin < allowWin && len(data) < targetCount && (len(profitDataAll) > 0 || len(winDataAll) > 0) {
+		permProfit := rng.Perm(len(profitDataAll))
+		permWin2 := rng.Perm(len(winDataAll))
+		pi, wi := 0, 0
+
+		// 估算初始倾向
+		remainingSlots := targetCount - len(data)
+		remainingWin := allowWin - totalWin
+		needFactor := 0.0
+		if remainingSlots > 0 {
+			needFactor = remainingWin.Ratio(perSpinBet * Money(remainingSlots))
+		}
+		basePProfit := needFactor
+		if basePProfit < 0.2 {
+			basePProfit = 0.2
+		}
+		if basePProfit > 0.8 {
+			basePProfit = 0.8
+		}
+		printf("[FB] 阶段2：动态占比起始 pProfit=%.3f (needFactor=%.3f)\n", basePProfit, needFactor)
+
+		maxOuter := len(profitDataAll) + len(winDataAll) + 1024
+		for outer := 0; outer < maxOuter; outer++ {
+			if totalWin >= allowWin || len(data) >= targetCount {
+				break
+			}
+			// 实时更新占比
+			remainingSlots = targetCount - len(data)
+			remainingWin = allowWin - totalWin
+			if remainingSlots <= 0 || remainingWin <= 0 {
+				break
+			}
+			needFactor = remainingWin.Ratio(perSpinBet * Money(remainingSlots))
+			pProfit := needFactor
+			if pProfit < 0.2 {
+				pProfit = 0.2
+			}
+			if pProfit > 0.8 {
+				pProfit = 0.8
+			}
+
+			chooseProfit := rng.Float64() < pProfit
+			appended := false
+
+			if chooseProfit && pi < len(permProfit) {
+				for pi < len(permProfit) {
+					cand := profitDataAll[permProfit[pi]]
+					pi++
+					if tryAppend(cand) {
+						appended = true
+						break
+					}
+				}
+			}
+			// 若未能加入或无可用 profit，则尝试 win
+			if !appended && wi < len(permWin2) {
+				for wi < len(permWin2) {
+					cand := winDataAll[permWin2[wi]]
+					wi++
+					if tryAppend(cand) {
+						appended = true
+						break
+					}
+				}
+			}
+			// 若先选 win 失败，再尝试 profit 兜底
+			if !appended && !chooseProfit && pi < len(permProfit) {
+				for pi < len(permProfit) {
+					cand := profitDataAll[permProfit[pi]]
+					pi++
+					if tryAppend(cand) {
+						appended = true
+						break
+					}
+				}
+			}
+			// 两边都无法加入，提前退出
+			if !appended {
+				break
+			}
+		}
+		printf("[FB] 阶段2完成：累计中奖=%.2f, 目标=%.2f, 数量=%d/%d\n", totalWin, allowWin, len(data), targetCount)
+	}
+
+	// 阶段3：若还需要补充（数量未达标），先用 winDataAll 的大额补 90% 的剩余名额
+	if len(data) < targetCount {
+		remainingSlots := targetCount - len(data)
+		stage3aSlots := int(math.Ceil(float64(remainingSlots) * stage3WinTopRatio))
+
+		if stage3aSlots > 0 && len(winDataAll) > 0 {
+			// winDataAll 按 aw DESC
+			winDesc := make([]GameResultData, len(winDataAll))
+			copy(winDesc, winDataAll)
+			sort.SliceStable(winDesc, func(i, j int) bool { return winDesc[i].AW > winDesc[j].AW })
+			for _, it := range winDesc {
+				if stage3aSlots == 0 || len(data) >= targetCount {
+					break
+				}
+				if tryAppend(it) {
+					stage3aSlots--
+				}
+			}
+		}
+
+		// 阶段3b：剩余名额根据缺口大小，用 profitDataAll 小额或大额补齐
+		if len(data) < targetCount {
+			remainingSlots = targetCount - len(data)
+			remainingWin := allowWin - totalWin
+			gapSmallThreshold := allowWin.MulRatio(0.02) // 小缺口阈值
+			if perSpinBet > gapSmallThreshold {
+				gapSmallThreshold = perSpinBet
+			}
+
+			// 若金额已足或接近上限，则直接跳过到数量兜底
+			if remainingWin > 0 && len(profitDataAll) > 0 {
+				// 按需选择排序方向
+				profit := make([]GameResultData, len(profitDataAll))
+				copy(profit, profitDataAll)
+				if remainingWin <= gapSmallThreshold {
+					sort.SliceStable(profit, func(i, j int) bool { return profit[i].AW < profit[j].AW }) // 小额优先
+				} else {
+					sort.SliceStable(profit, func(i, j int) bool { return profit[i].AW > profit[j].AW }) // 大额优先
+				}
+
+				for _, it := range profit {
+					if remainingSlots == 0 || len(data) >= targetCount {
+						break
+					}
+					// 若已经达到目标金额，仅在不超过上限时允许继续；核心由上限约束
+					if tryAppend(it) {
+						remainingSlots--
+						remainingWin = allowWin - totalWin
+						if remainingWin <= 0 {
+							// 金额已达标，后续数量不足交由阶段4处理
+							break
+						}
+					}
+				}
+			}
+		}
+	}
+
+	// 阶段4：数量兜底，优先无放回补不中奖；若仍不足，再允许重复不中奖补满
+	if len(data) < targetCount && len(noWinDataAll) > 0 {
+		need := targetCount - len(data)
+		// 先无放回
+		perm := rng.Perm(len(noWinDataAll))
+		for _, idx := range perm {
+			if need == 0 {
+				break
+			}
+			item := noWinDataAll[idx]
+			if _, ok := used[item.ID]; ok {
+				continue
+			}
+			data = append(data, item)
+			used[item.ID] = struct{}{}
+			need--
+		}
+		// 再重复补齐（仅对不中奖允许重复，以保证条数）
+		if need > 0 {
+			for i := 0; i < need; i++ {
+				data = append(data, noWinDataAll[i%len(noWinDataAll)])
+			}
+		}
+	}
+
+	// 最终统计与保存
+	printf("📊 [FB] 最终验证: 期望 %d 条, 实际 %d 条\n", targetCount, len(data))
+	var finalTotalWin Money
+	for _, it := range data {
+		finalTotalWin += it.AW
+	}
+	finalRTP := finalTotalWin.Ratio(totalBet)
+	printf("✅ [FB] 档位: %.0f, 目标RTP: %.6f, 实际RTP: %.6f, 偏差: %.6f\n", rtpLevel, rtp, finalRTP, math.Abs(finalRTP-rtp))
+	if err := checkRtpWindow(rtpLevel, finalTotalWin, lowerBound, upperBound, in, printf); err != nil {
+		return nil, err
+	}
+
+	// 重复率统计（按 id 去重）
+	uniq := make(map[int]int, len(data))
+	for _, it := range data {
+		uniq[it.ID]++
+	}
+	dupCount := 0
+	for _, c := range uniq {
+		if c > 1 {
+			dupCount += c - 1
+		}
+	}
+	dupRate := 0.0
+	if n := len(data); n > 0 {
+		dupRate = float64(dupCount) / float64(n)
+	}
+	printf("🔎 [FB] 去重统计: 总数=%d, 唯一=%d, 重复=%d, 重复率=%.4f\n", len(data), len(uniq), dupCount, dupRate)
+
+	return newSelectionResult(data, totalBet), nil
+}
+
+// checkRtpWindow 最终校验：总中奖金额必须落在档位验收区间对应的金额范围内（按分比较，避免浮点误差）
+func checkRtpWindow(rtpLevel float64, totalWin, minWin, maxWin Money, in *SelectionInput, printf func(format string, a ...interface{})) error {
+	finalRTP := totalWin.Ratio(in.TotalBet)
+	if totalWin < minWin || totalWin > maxWin {
+		return fmt.Errorf("❌ 档位 %.0f RTP验证失败: 当前RTP %.4f 不在验收区间 [%.4f, %.4f] 内", rtpLevel, finalRTP, in.MinRtp, in.MaxRtp)
+	}
+	printf("🎯 档位 %.0f RTP验证通过: %.4f 在区间 [%.4f, %.4f] 内\n", rtpLevel, finalRTP, in.MinRtp, in.MaxRtp)
+	return nil
+}
@@ -0,0 +1,319 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"log"
+	"math/rand"
+	"strings"
+	"time"
+)
+
+// CandidatePools 候选数据池，同一游戏同一策略下所有任务共享只读
+type CandidatePools struct {
+	Win    []GameResultData // 中奖数据
+	Profit []GameResultData // 中奖且盈利数据（部分策略不使用）
+	NoWin  []GameResultData // 不中奖数据
+}
+
+// PrizeQuotas 单个文件内大奖(gwt=2)、巨奖(gwt=3)、超级巨奖(gwt=4)的数量上限
+type PrizeQuotas struct {
+	Big       int
+	Mega      int
+	SuperMega int
+}
+
+// StrategyShape 策略的生成规模：每文件条数、每档位文件数、单次投注额、是否购买夺宝
+type StrategyShape struct {
+	DataNum    int
+	TestNum    int
+	PerSpinBet Money
+	IsFb       bool
+	Quotas     PrizeQuotas
+	// UnlimitedPrizes 策略不限制大奖/巨奖/超级巨奖数量（校验时不检查 Quotas）
+	UnlimitedPrizes bool
+}
+
+// TotalBet 单个文件的总投注
+func (s StrategyShape) TotalBet() Money {
+	return s.PerSpinBet * Money(s.DataNum)
+}
+
+// SelectionInput 单个任务（游戏、档位、第几次）的选择输入
+type SelectionInput struct {
+	Config     *Config
+	Pools      *CandidatePools
+	RtpLevel   float64
+	Rtp        float64
+	MinRtp     float64 // RTP 验收区间下限，选择与最终校验均以 [MinRtp, MaxRtp] 为准
+	MaxRtp     float64 // RTP 验收区间上限
+	TotalBet   Money
+	PerSpinBet Money
+	Count      int
+	Quotas     PrizeQuotas
+	Rng        *rand.Rand                            // 任务随机源，选择过程只能使用该随机源以保证可复现
+	Logf       func(format string, a ...interface{}) // 任务日志，按块输出
+}
+
+// SelectionResult 选择结果及统计
+type SelectionResult struct {
+	Data           []GameResultData
+	TotalWin       Money
+	RTP            float64
+	BigCount       int
+	MegaCount      int
+	SuperMegaCount int
+}
+
+// newSelectionResult 根据选中数据计算统计信息
+func newSelectionResult(data []GameResultData, totalBet Money) *SelectionResult {
+	result := &SelectionResult{Data: data}
+	for _, item := range data {
+		result.TotalWin += item.AW
+		switch item.GWT {
+		case 2:
+			result.BigCount++
+		case 3:
+			result.MegaCount++
+		case 4:
+			result.SuperMegaCount++
+		}
+	}
+	result.RTP = result.TotalWin.Ratio(totalBet)
+	return result
+}
+
+// SelectionStrategy 数据选择策略
+// 策略只负责"从候选池中选出一个文件的数据"，加载配置、并发调度、落盘由统一流程完成
+type SelectionStrategy interface {
+	// Name 策略名称，同时作为命令名和输出文件中的 mode
+	Name() string
+	// Description 策略说明，用于帮助信息和启动日志
+	Description() string
+	// Levels 策略使用的 RTP 档位表
+	Levels(config *Config) []RtpLevel
+	// RtpWindow 档位未配置 minRtp/maxRtp 时单个文件的默认 RTP 验收区间 [min, max]
+	RtpWindow(config *Config, level RtpLevel) (float64, float64)
+	// Shape 策略的生成规模
+	Shape(config *Config) StrategyShape
+	// LoadPools 从源表加载候选数据池
+	LoadPools(db *Database) (*CandidatePools, error)
+	// Select 为单个任务选出数据
+	Select(in *SelectionInput) (*SelectionResult, error)
+}
+
+var (
+	strategyRegistry = make(map[string]SelectionStrategy)
+	strategyOrder    []string
+)
+
+// RegisterStrategy 注册选择策略，名称重复视为编程错误
+func RegisterStrategy(s SelectionStrategy) {
+	name := s.Name()
+	if _, exists := strategyRegistry[name]; exists {
+		panic(fmt.Sprintf("策略重复注册: %s", name))
+	}
+	strategyRegistry[name] = s
+	strategyOrder = append(strategyOrder, name)
+}
+
+// LookupStrategy 按名称查找策略
+func LookupStrategy(name string) (SelectionStrategy, bool) {
+	s, ok := strategyRegistry[name]
+	return s, ok
+}
+
+// StrategyNames 按注册顺序返回所有策略名称
+func StrategyNames() []string {
+	names := make([]string, len(strategyOrder))
+	copy(names, strategyOrder)
+	return names
+}
+
+func init() {
+	RegisterStrategy(generateStrategy{})
+	RegisterStrategy(generate2Strategy{})
+	RegisterStrategy(generate3Strategy{})
+	RegisterStrategy(generateFbStrategy{})
+}
+
+// levelWindow 档位的 RTP 验收区间：档位配置的 minRtp/maxRtp 优先，未配置的一侧使用策略默认区间
+func levelWindow(strategy SelectionStrategy, config *Config, level RtpLevel) (float64, float64) {
+	minRtp, maxRtp := strategy.RtpWindow(config, level)
+	if level.MinRtp > 0 {
+		minRtp = level.MinRtp
+	}
+	if level.MaxRtp > 0 {
+		maxRtp = level.MaxRtp
+	}
+	return minRtp, maxRtp
+}
+
+// defaultPrizeQuotas 按配置的奖项比例计算单个文件的奖项上限
+func defaultPrizeQuotas(config *Config, dataNum int) PrizeQuotas {
+	return PrizeQuotas{
+		Big:       int(float64(dataNum) * config.PrizeRatios.BigPrize),
+		Mega:      int(float64(dataNum) * config.PrizeRatios.MegaPrize),
+		SuperMega: int(float64(dataNum) * config.PrizeRatios.SuperMegaPrize),
+	}
+}
+
+// runStrategyTask 执行单个生成任务：选择数据、打乱顺序并写入输出文件，返回该文件的生成清单条目
+func runStrategyTask(config *Config, strategy SelectionStrategy, pools *CandidatePools, level RtpLevel, testNumber int, seed int64, outputDir string) (*ManifestFile, error) {
+	var logBuf bytes.Buffer
+	printf := func(format string, a ...interface{}) {
+		fmt.Fprintf(&logBuf, format, a...)
+	}
+	defer func() {
+		outputMu.Lock()
+		fmt.Print(logBuf.String())
+		outputMu.Unlock()
+	}()
+	testStartTime := time.Now()
+
+	shape := strategy.Shape(config)
+	name := strategy.Name()
+
+	// 任务头分隔线
+	printf("\n========== [TASK BEGIN %s] RtpNo: %.0f | Test: %d | Seed: %d | %s =========\n", name, level.RtpNo, testNumber, seed, time.Now().Format(time.RFC3339))
+
+	// 每任务独立随机源，种子由调用方派生，便于复现
+	rng := rand.New(rand.NewSource(seed))
+	minRtp, maxRtp := levelWindow(strategy, config, level)
+	result, err := strategy.Select(&SelectionInput{
+		Config:     config,
+		Pools:      pools,
+		RtpLevel:   level.RtpNo,
+		Rtp:        level.Rtp,
+		MinRtp:     minRtp,
+		MaxRtp:     maxRtp,
+		TotalBet:   shape.TotalBet(),
+		PerSpinBet: shape.PerSpinBet,
+		Count:      shape.DataNum,
+		Quotas:     shape.Quotas,
+		Rng:        rng,
+		Logf:       printf,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	// 打乱输出顺序
+	data := result.Data
+	rng.Shuffle(len(data), func(i, j int) {
+		data[i], data[j] = data[j], data[i]
+	})
+	entry, err := saveToJSON(data, config, level.RtpNo, testNumber, seed, name, outputDir)
+	if err != nil {
+		return nil, fmt.Errorf("保存JSON文件失败: %v", err)
+	}
+
+	// 任务尾分隔线
+	printf("========== [TASK END %s]   RtpNo: %.0f | Test: %d =========\n\n", name, level.RtpNo, testNumber)
+	printf("⏱️  RTP等级 %.0f (第%d次生成) 耗时: %v\n", level.RtpNo, testNumber, time.Since(testStartTime))
+	return entry, nil
+}
+
+// runStrategyForGame 按策略为单个游戏生成全部档位文件
+func runStrategyForGame(strategy SelectionStrategy, config *Config, db *Database, baseSeed int64) error {
+	scheduler := newGenerationScheduler(db, config, baseSeed)
+	scheduler.AddGame(strategy, config)
+	if errs := scheduler.Run(); len(errs) > 0 {
+		return errs[0]
+	}
+	return nil
+}
+
+// runGenerateMode 单游戏生成：按名称选择策略，为 config.yaml 中的当前游戏（或 --game 指定的游戏）生成数据
+func runGenerateMode(strategyName string, gameID int, baseSeed int64) {
+	// 记录程序开始时间
+	startTime := time.Now()
+
+	strategy, ok := LookupStrategy(strategyName)
+	if !ok {
+		log.Fatalf("❌ 不支持的生成模式: %s（支持: %s）", strategyName, strings.Join(StrategyNames(), ", "))
+	}
+
+	// 加载配置文件
+	config, err := LoadConfig(configPath)
+	if err != nil {
+		log.Fatalf("加载配置文件失败: %v", err)
+	}
+	if gameID > 0 {
+		config = config.ForGameID(gameID)
+	}
+	if strategy.Shape(config).IsFb && !config.Game.IsFb {
+		fmt.Printf("⚠️ [%s] 当前游戏未启用购买夺宝 (game.is_fb=false)，退出。\n", strategyName)
+		return
+	}
+
+	// 连接数据库
+	db, err := NewDatabase(config, "")
+	if err != nil {
+		log.Fatalf("数据库连接失败: %v", err)
+	}
+	defer db.Close()
+
+	if err := runStrategyForGame(strategy, config, db, baseSeed); err != nil {
+		log.Fatalf("❌ [%s] 生成失败: %v", strategyName, err)
+	}
+
+	// 计算并输出整个程序的总耗时
+	fmt.Printf("\n🎉 [%s] RTP数据筛选和保存完成！\n", strategyName)
+	fmt.Printf("⏱️  整个程序总耗时: %v\n", time.Since(startTime))
+}
+
+// runMultiGameMode 运行多游戏生成模式
+func runMultiGameMode(mode string, baseSeed int64) {
+	// 记录程序开始时间
+	startTime := time.Now()
+
+	// 加载配置文件
+	config, err := LoadConfig(configPath)
+	if err != nil {
+		log.Fatalf("加载配置文件失败: %v", err)
+	}
+
+	// 验证生成模式
+	strategy, ok := LookupStrategy(mode)
+	if !ok {
+		fmt.Printf("❌ 无效的生成模式: %s\n", mode)
+		fmt.Printf("支持的模式: %s\n", strings.Join(StrategyNames(), ", "))
+		return
+	}
+
+	// 检查是否启用多游戏模式
+	if !config.MultiGame.Enabled {
+		fmt.Println("⚠️ 多游戏模式未启用，请设置 multi_game.enabled: true")
+		return
+	}
+
+	if len(config.MultiGame.Games) == 0 {
+		fmt.Println("⚠️ 未配置任何游戏，请检查 multi_game.games 配置")
+		return
+	}
+
+	fmt.Printf("🎮 多游戏模式启动，生成模式: %s，共配置 %d 个游戏\n", mode, len(config.MultiGame.Games))
+	for i, game := range config.MultiGame.Games {
+		fmt.Printf("  游戏 %d: ID=%d, BL=%.0f, IsFb=%t\n", i+1, game.ID, game.BL, game.IsFb)
+	}
+
+	// 连接数据库
+	db, err := NewDatabase(config, "")
+	if err != nil {
+		log.Fatalf("数据库连接失败: %v", err)
+	}
+	defer db.Close()
+
+	// 所有游戏的 (档位, 第几次) 任务共用一个调度器，并发数受全局与单游戏上限共同限制
+	scheduler := newGenerationScheduler(db, config, baseSeed)
+	for _, gameConfig := range config.MultiGame.Games {
+		scheduler.AddGame(strategy, config.ForGame(gameConfig))
+	}
+	for _, err := range scheduler.Run() {
+		log.Printf("❌ %v", err)
+	}
+
+	totalDuration := time.Since(startTime)
+	fmt.Printf("\n🎉 所有游戏生成完成！总耗时: %v\n", totalDuration)
+}
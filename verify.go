@@ -0,0 +1,335 @@
+package main
+
+import (
+	"crypto/sha256"
+	"encoding/json"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// rtpEpsilon RTP 区间比较的浮点容差
+const rtpEpsilon = 1e-9
+
+// FileVerifyResult 单个输出文件的校验结果
+type FileVerifyResult struct {
+	Name            string
+	Mode            string
+	RtpLevel        int
+	SrNumber        int
+	Rows            int
+	SumAW           Money
+	SumTB           Money
+	Bet             Money // RTP 的分母：每注投注 × 条数，购买夺宝按购买价计算
+	RTP             float64
+	MinRtp          float64
+	MaxRtp          float64
+	Big             int
+	Mega            int
+	SuperMega       int
+	Duplicates      int // 中奖行重复使用次数
+	NoWinDuplicates int // 不中奖行重复使用次数（补满条数时允许）
+	Violations      []string
+}
+
+func (r *FileVerifyResult) addViolation(format string, a ...interface{}) {
+	r.Violations = append(r.Violations, fmt.Sprintf(format, a...))
+}
+
+// resultFileStats 流式统计一个输出文件的数据部分
+type resultFileStats struct {
+	Rows            int
+	SumAW           Money
+	SumTB           Money
+	Big             int
+	Mega            int
+	SuperMega       int
+	Duplicates      int
+	NoWinDuplicates int
+}
+
+// scanResultFile 流式读取输出文件：解析头部并逐行统计，不把整个文件加载到内存
+// 输出行不含源表 id，重复使用按行内容（原始 JSON 字节）的哈希判断
+func scanResultFile(path string) (*ResultFileHeader, *resultFileStats, error) {
+	fh, err := openResultFile(path)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer fh.Close()
+
+	dec := json.NewDecoder(fh)
+	header, err := readResultHeader(dec)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	var stats resultFileStats
+	seen := make(map[[sha256.Size]byte]struct{})
+	for dec.More() {
+		var raw json.RawMessage
+		if err := dec.Decode(&raw); err != nil {
+			return nil, nil, fmt.Errorf("解析第 %d 条记录失败: %v", stats.Rows+1, err)
+		}
+		var row ResultRow
+		if err := json.Unmarshal(raw, &row); err != nil {
+			return nil, nil, fmt.Errorf("解析第 %d 条记录失败: %v", stats.Rows+1, err)
+		}
+		stats.Rows++
+		stats.SumAW += row.AW
+		stats.SumTB += row.TB
+		switch row.GWT {
+		case 2:
+			stats.Big++
+		case 3:
+			stats.Mega++
+		case 4:
+			stats.SuperMega++
+		}
+		sum := sha256.Sum256(raw)
+		if _, dup := seen[sum]; dup {
+			if row.AW > 0 {
+				stats.Duplicates++
+			} else {
+				stats.NoWinDuplicates++
+			}
+		} else {
+			seen[sum] = struct{}{}
+		}
+	}
+	if err := readResultTrailer(dec); err != nil {
+		return nil, nil, err
+	}
+	return header, &stats, nil
+}
+
+// defaultModeForLevel 文件未记录 mode 时按目录和档位表推断生成模式
+func defaultModeForLevel(config *Config, isFb bool, rtpNo float64) string {
+	if isFb {
+		return "generateFb"
+	}
+	if s, ok := LookupStrategy("generate"); ok {
+		if _, found := findRtpLevel(s.Levels(config), rtpNo); found {
+			return "generate"
+		}
+	}
+	if s, ok := LookupStrategy("generate3"); ok {
+		if _, found := findRtpLevel(s.Levels(config), rtpNo); found {
+			return "generate3"
+		}
+	}
+	return "generate"
+}
+
+// verifyResultFile 校验单个输出文件：条数、RTP 区间、奖项数量、重复使用
+func verifyResultFile(config *Config, isFb bool, path string, fileLevel, fileSrNumber int) *FileVerifyResult {
+	result := &FileVerifyResult{Name: filepath.Base(path), RtpLevel: fileLevel, SrNumber: fileSrNumber}
+
+	header, stats, err := scanResultFile(path)
+	if err != nil {
+		result.addViolation("读取失败: %v", err)
+		return result
+	}
+	result.Rows = stats.Rows
+	result.SumAW = stats.SumAW
+	result.SumTB = stats.SumTB
+	result.Big, result.Mega, result.SuperMega = stats.Big, stats.Mega, stats.SuperMega
+	result.Duplicates, result.NoWinDuplicates = stats.Duplicates, stats.NoWinDuplicates
+
+	if header.RtpLevel != fileLevel || header.SrNumber != fileSrNumber {
+		result.addViolation("头部(rtpLevel=%d, srNumber=%d)与文件名不一致", header.RtpLevel, header.SrNumber)
+	}
+
+	mode := header.Mode
+	if mode == "" {
+		mode = defaultModeForLevel(config, isFb, float64(fileLevel))
+	}
+	result.Mode = mode
+	strategy, ok := LookupStrategy(mode)
+	if !ok {
+		result.addViolation("未知生成模式: %s", mode)
+		return result
+	}
+	shape := strategy.Shape(config)
+	if shape.IsFb != isFb {
+		result.addViolation("模式 %s 与目录不匹配", mode)
+	}
+	// 与生成时一致按每注投注计算 RTP；购买夺宝行内的 tb 是基础投注，不含购买倍数
+	result.Bet = shape.PerSpinBet * Money(stats.Rows)
+	result.RTP = stats.SumAW.Ratio(result.Bet)
+	level, ok := findRtpLevel(strategy.Levels(config), float64(fileLevel))
+	if !ok {
+		result.addViolation("档位 %d 不在模式 %s 的档位表中", fileLevel, mode)
+		return result
+	}
+
+	if stats.Rows != shape.DataNum {
+		result.addViolation("条数 %d ≠ 期望 %d", stats.Rows, shape.DataNum)
+	}
+	result.MinRtp, result.MaxRtp = levelWindow(strategy, config, level)
+	if result.Bet <= 0 {
+		result.addViolation("总投注为 0")
+	} else if result.RTP < result.MinRtp-rtpEpsilon || result.RTP > result.MaxRtp+rtpEpsilon {
+		result.addViolation("RTP %.6f 超出区间 [%.6f, %.6f]", result.RTP, result.MinRtp, result.MaxRtp)
+	}
+	if !shape.UnlimitedPrizes {
+		q := shape.Quotas
+		if stats.Big > q.Big {
+			result.addViolation("大奖 %d > 上限 %d", stats.Big, q.Big)
+		}
+		if stats.Mega > q.Mega {
+			result.addViolation("巨奖 %d > 上限 %d", stats.Mega, q.Mega)
+		}
+		if stats.SuperMega > q.SuperMega {
+			result.addViolation("超级巨奖 %d > 上限 %d", stats.SuperMega, q.SuperMega)
+		}
+	}
+	if stats.Duplicates > 0 {
+		result.addViolation("中奖数据重复使用 %d 次", stats.Duplicates)
+	}
+	return result
+}
+
+// runVerifyMode 校验生成目录：verify --game <id> [--fb]
+// 逐个流式读取 output/<gameId>[_fb] 下的结果文件，存在任何违规时以非零状态退出
+func runVerifyMode(gameID int, isFb bool) {
+	config, err := LoadConfig(configPath)
+	if err != nil {
+		log.Fatalf("加载配置文件失败: %v", err)
+	}
+	config = config.ForGameID(gameID)
+
+	dir := gameOutputDir(gameID, isFb)
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		log.Fatalf("❌ 读取目录 %s 失败: %v", dir, err)
+	}
+	re := resultFileRegexp(config)
+
+	fmt.Printf("🔍 校验目录: %s\n", dir)
+	var results []*FileVerifyResult
+	for _, entry := range entries {
+		if entry.IsDir() {
+			continue
+		}
+		level, srNumber, ok := parseResultFileName(re, entry.Name())
+		if !ok {
+			continue
+		}
+		results = append(results, verifyResultFile(config, isFb, filepath.Join(dir, entry.Name()), level, srNumber))
+	}
+	if len(results) == 0 {
+		log.Fatalf("❌ 在 %s 未找到结果文件", dir)
+	}
+	checkGenerationManifest(dir, results)
+
+	// 按出现过的模式检查档位 × 次数是否齐全
+	present := make(map[string]bool)
+	modes := make(map[string]bool)
+	for _, r := range results {
+		present[fmt.Sprintf("%s|%d|%d", r.Mode, r.RtpLevel, r.SrNumber)] = true
+		if r.Mode != "" {
+			modes[r.Mode] = true
+		}
+	}
+	for mode := range modes {
+		strategy, ok := LookupStrategy(mode)
+		if !ok {
+			continue
+		}
+		shape := strategy.Shape(config)
+		for _, level := range strategy.Levels(config) {
+			for n := 1; n <= shape.TestNum; n++ {
+				if present[fmt.Sprintf("%s|%d|%d", mode, int(level.RtpNo), n)] {
+					continue
+				}
+				missing := &FileVerifyResult{
+					Name:     resultFileName(config, level.RtpNo, n),
+					Mode:     mode,
+					RtpLevel: int(level.RtpNo),
+					SrNumber: n,
+				}
+				missing.addViolation("缺少文件")
+				results = append(results, missing)
+			}
+		}
+	}
+
+	sort.Slice(results, func(i, j int) bool {
+		if results[i].RtpLevel != results[j].RtpLevel {
+			return results[i].RtpLevel < results[j].RtpLevel
+		}
+		return results[i].SrNumber < results[j].SrNumber
+	})
+
+	failed := printVerifyTable(results)
+	if failed > 0 {
+		fmt.Printf("\n❌ 校验未通过: %d/%d 个文件存在违规\n", failed, len(results))
+		os.Exit(1)
+	}
+	fmt.Printf("\n✅ 校验通过: %d 个文件全部符合要求\n", len(results))
+}
+
+// checkGenerationManifest 目录中有 manifest.json 时，核对已记录文件的 sha256，确认文件仍是清单中那次生成的产物
+func checkGenerationManifest(dir string, results []*FileVerifyResult) {
+	manifest, err := readGenerationManifest(dir)
+	if err != nil {
+		fmt.Printf("⚠️ %v\n", err)
+		return
+	}
+	if manifest == nil {
+		fmt.Printf("ℹ️ 目录中没有 %s，跳过来源核对\n", manifestFileName)
+		return
+	}
+	entries := make(map[string]ManifestFile, len(manifest.Files))
+	for _, f := range manifest.Files {
+		entries[f.Name] = f
+	}
+	checked, untracked := 0, 0
+	for _, r := range results {
+		entry, ok := entries[r.Name]
+		if !ok {
+			untracked++
+			continue
+		}
+		sum, err := fileSHA256(filepath.Join(dir, r.Name))
+		if err != nil {
+			r.addViolation("计算校验和失败: %v", err)
+			continue
+		}
+		checked++
+		if sum != entry.SHA256 {
+			r.addViolation("sha256 与 %s 记录不一致（运行 %s）", manifestFileName, entry.Run)
+		}
+	}
+	fmt.Printf("🧾 已按 %s 核对 %d 个文件的 sha256", manifestFileName, checked)
+	if untracked > 0 {
+		fmt.Printf("，%d 个文件未记录在清单中", untracked)
+	}
+	fmt.Println()
+}
+
+// printVerifyTable 打印逐文件校验表，返回存在违规的文件数
+func printVerifyTable(results []*FileVerifyResult) int {
+	fmt.Printf("\n%-26s %-11s %7s %12s %-21s %-12s %6s  %s\n",
+		"文件", "模式", "条数", "RTP", "区间", "大/巨/超巨", "重复", "结果")
+	fmt.Println(strings.Repeat("-", 110))
+	failed := 0
+	for _, r := range results {
+		status := "✅"
+		if len(r.Violations) > 0 {
+			status = "❌ " + strings.Join(r.Violations, "; ")
+			failed++
+		}
+		window := ""
+		if r.MaxRtp > 0 {
+			window = fmt.Sprintf("[%.4f, %.4f]", r.MinRtp, r.MaxRtp)
+		}
+		fmt.Printf("%-26s %-11s %7d %12.6f %-21s %-12s %6d  %s\n",
+			r.Name, r.Mode, r.Rows, r.RTP, window,
+			fmt.Sprintf("%d/%d/%d", r.Big, r.Mega, r.SuperMega), r.Duplicates, status)
+	}
+	return failed
+}
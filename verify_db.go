@@ -0,0 +1,208 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"math"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// SliceVerifyResult 目标表中一个 (rtpLevel, srNumber) 切片的校验结果
+type SliceVerifyResult struct {
+	OutputSliceStats
+	Mode       string
+	RTP        float64
+	MinRtp     float64
+	MaxRtp     float64
+	Violations []string
+}
+
+func (r *SliceVerifyResult) addViolation(format string, a ...interface{}) {
+	r.Violations = append(r.Violations, fmt.Sprintf(format, a...))
+}
+
+// splitDbRtpLevel 拆分目标表中的 rtpLevel：购买夺宝数据导入时为档位+0.1（如 13.1）
+func splitDbRtpLevel(v float64) (rtpNo float64, isFb bool) {
+	rounded := math.Round(v*10) / 10
+	whole := math.Floor(rounded)
+	return whole, math.Abs(rounded-whole-0.1) < 1e-6
+}
+
+// verifySlice 校验单个切片：档位是否存在、RTP 区间、条数、srId 连续性
+func verifySlice(config *Config, stats OutputSliceStats, normalMode string) *SliceVerifyResult {
+	result := &SliceVerifyResult{OutputSliceStats: stats}
+	rtpNo, isFb := splitDbRtpLevel(stats.RtpLevel)
+
+	mode := normalMode
+	if isFb {
+		mode = "generateFb"
+	} else if mode == "" {
+		mode = defaultModeForLevel(config, false, rtpNo)
+	}
+	result.Mode = mode
+	result.RTP = stats.SumWin.Ratio(stats.SumBet)
+
+	strategy, ok := LookupStrategy(mode)
+	if !ok {
+		result.addViolation("未知生成模式: %s", mode)
+		return result
+	}
+	level, ok := findRtpLevel(strategy.Levels(config), rtpNo)
+	if !ok {
+		result.addViolation("档位 %.1f 不在模式 %s 的档位表中", stats.RtpLevel, mode)
+		return result
+	}
+	shape := strategy.Shape(config)
+
+	result.MinRtp, result.MaxRtp = levelWindow(strategy, config, level)
+	if stats.SumBet <= 0 {
+		result.addViolation("总投注为 0")
+	} else if result.RTP < result.MinRtp-rtpEpsilon || result.RTP > result.MaxRtp+rtpEpsilon {
+		result.addViolation("RTP %.6f 超出区间 [%.6f, %.6f]", result.RTP, result.MinRtp, result.MaxRtp)
+	}
+	if stats.Count != shape.DataNum {
+		result.addViolation("条数 %d ≠ 期望 %d", stats.Count, shape.DataNum)
+	}
+	if stats.MinSrId != 1 || stats.MaxSrId != stats.Count || stats.DistinctId != stats.Count {
+		result.addViolation("srId 不连续: 范围 [%d, %d], 去重 %d, 条数 %d", stats.MinSrId, stats.MaxSrId, stats.DistinctId, stats.Count)
+	}
+	if stats.SrNumber < 1 || stats.SrNumber > shape.TestNum {
+		result.addViolation("srNumber %d 超出范围 [1, %d]", stats.SrNumber, shape.TestNum)
+	}
+	return result
+}
+
+// runVerifyDbMode 审计已导入的目标表：verify-db --game <id> [--env e] [--mode m]
+// 按 rtpLevel、srNumber 聚合，校验 RTP（含 +0.1 的购买夺宝档位）、条数、srId 连续性与 srNumber 覆盖
+func runVerifyDbMode(gameID int, env string, modeArg string) {
+	normalMode := ""
+	if modeArg != "" {
+		strategy, ok := LookupStrategy(modeArg)
+		if !ok || strategy.Shape(&Config{}).IsFb {
+			log.Fatalf("❌ 无效的普通模式: %s", modeArg)
+		}
+		normalMode = modeArg
+	}
+
+	config, err := LoadConfig(configPath)
+	if err != nil {
+		log.Fatalf("加载配置文件失败: %v", err)
+	}
+	config = config.ForGameID(gameID)
+
+	db, err := NewDatabase(config, env)
+	if err != nil {
+		log.Fatalf("数据库连接失败: %v", err)
+	}
+	defer db.Close()
+
+	tableName := fmt.Sprintf("%s%d", config.Tables.OutputTablePrefix, gameID)
+	fmt.Printf("🔍 审计目标表: %s\n", tableName)
+	slices, err := db.AggregateOutputTable(tableName)
+	if err != nil {
+		log.Fatalf("❌ %v", err)
+	}
+	if len(slices) == 0 {
+		log.Fatalf("❌ 目标表 %s 中没有数据", tableName)
+	}
+
+	var results []*SliceVerifyResult
+	present := make(map[string]bool)
+	modes := make(map[string]bool)
+	for _, s := range slices {
+		r := verifySlice(config, s, normalMode)
+		results = append(results, r)
+		rtpNo, _ := splitDbRtpLevel(s.RtpLevel)
+		present[fmt.Sprintf("%s|%.0f|%d", r.Mode, rtpNo, s.SrNumber)] = true
+		modes[r.Mode] = true
+	}
+
+	// srNumber 覆盖：按出现过的模式检查档位 × 次数是否齐全
+	var missing []string
+	for mode := range modes {
+		strategy, ok := LookupStrategy(mode)
+		if !ok {
+			continue
+		}
+		shape := strategy.Shape(config)
+		for _, level := range strategy.Levels(config) {
+			var lack []string
+			for n := 1; n <= shape.TestNum; n++ {
+				if !present[fmt.Sprintf("%s|%.0f|%d", mode, level.RtpNo, n)] {
+					lack = append(lack, strconv.Itoa(n))
+				}
+			}
+			if len(lack) > 0 {
+				display := level.RtpNo
+				if shape.IsFb {
+					display += 0.1
+				}
+				missing = append(missing, fmt.Sprintf("[%s] rtpLevel %g 缺少 srNumber: %s", mode, display, strings.Join(lack, ",")))
+			}
+		}
+	}
+	sort.Strings(missing)
+
+	failed := printVerifyDbReport(results)
+	if len(missing) > 0 {
+		fmt.Println("\n❌ srNumber 覆盖不完整:")
+		for _, m := range missing {
+			fmt.Printf("   %s\n", m)
+		}
+	}
+	if failed > 0 || len(missing) > 0 {
+		fmt.Printf("\n❌ 审计未通过: %d/%d 个切片存在违规, %d 个档位缺少 srNumber\n", failed, len(results), len(missing))
+		os.Exit(1)
+	}
+	fmt.Printf("\n✅ 审计通过: %d 个切片全部符合要求\n", len(results))
+}
+
+// printVerifyDbReport 打印按档位汇总的 RTP 以及逐切片校验结果，返回存在违规的切片数
+func printVerifyDbReport(results []*SliceVerifyResult) int {
+	// 按档位汇总（对应 sum(win)/sum(bet) group by "rtpLevel"）
+	type levelSummary struct {
+		count  int
+		slices int
+		bet    Money
+		win    Money
+	}
+	summaries := make(map[float64]*levelSummary)
+	var levels []float64
+	for _, r := range results {
+		s, ok := summaries[r.RtpLevel]
+		if !ok {
+			s = &levelSummary{}
+			summaries[r.RtpLevel] = s
+			levels = append(levels, r.RtpLevel)
+		}
+		s.count += r.Count
+		s.slices++
+		s.bet += r.SumBet
+		s.win += r.SumWin
+	}
+	sort.Float64s(levels)
+	fmt.Printf("\n%-10s %8s %10s %12s\n", "rtpLevel", "srNumber", "条数", "RTP")
+	fmt.Println(strings.Repeat("-", 46))
+	for _, lv := range levels {
+		s := summaries[lv]
+		fmt.Printf("%-10g %8d %10d %12.6f\n", lv, s.slices, s.count, s.win.Ratio(s.bet))
+	}
+
+	// 逐切片结果，仅展开存在违规的切片
+	failed := 0
+	for _, r := range results {
+		if len(r.Violations) == 0 {
+			continue
+		}
+		if failed == 0 {
+			fmt.Printf("\n%-10s %8s %-11s %8s %12s  %s\n", "rtpLevel", "srNumber", "模式", "条数", "RTP", "违规")
+			fmt.Println(strings.Repeat("-", 90))
+		}
+		failed++
+		fmt.Printf("%-10g %8d %-11s %8d %12.6f  ❌ %s\n", r.RtpLevel, r.SrNumber, r.Mode, r.Count, r.RTP, strings.Join(r.Violations, "; "))
+	}
+	return failed
+}
@@ -0,0 +1,34 @@
+package main
+
+import (
+	"path/filepath"
+	"testing"
+)
+
+func TestVerifyResultFileFb(t *testing.T) {
+	// 购买价 = 0.1 * 1 * 10 * 50 = 50.00，4 条共 200.00；行内 tb 为基础投注 1.00
+	config := &Config{}
+	config.Tables.OutputTablePrefix = "GameResults"
+	config.Tables.DataNumFb = 4
+	config.Tables.DataTableNumFb = 1
+	config.Bet.CS, config.Bet.ML, config.Bet.BL, config.Bet.FB = 0.1, 1, 10, 50
+	config.RtpLevels.Fb = []RtpLevel{{RtpNo: 1, Rtp: 0.6}}
+
+	result := verifyResultFile(config, true, filepath.Join("testdata", "fb", "GameResults_1_1.json"), 1, 1)
+	if len(result.Violations) > 0 {
+		t.Fatalf("期望校验通过，违规: %v", result.Violations)
+	}
+	if result.SumAW != 12020 || result.SumTB != 400 || result.Bet != 20000 {
+		t.Fatalf("合计 aw=%d tb=%d bet=%d，期望 12020/400/20000 分", result.SumAW, result.SumTB, result.Bet)
+	}
+	if result.RTP != 0.601 {
+		t.Fatalf("RTP = %v，期望按购买价计算为 0.601", result.RTP)
+	}
+
+	// 购买倍数配置错误时 RTP 超出区间
+	config.Bet.FB = 40
+	result = verifyResultFile(config, true, filepath.Join("testdata", "fb", "GameResults_1_1.json"), 1, 1)
+	if len(result.Violations) != 1 {
+		t.Fatalf("期望 1 项 RTP 违规，得到 %v", result.Violations)
+	}
+}